@@ -3,15 +3,26 @@ package main
 import (
 	"log"
 	"os"
+	"time"
+	_ "time/tzdata" // Database IANA time zone untuk preferences user (container tanpa tzdata)
 
 	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/jobs"
+	"github.com/MashuNakamura/todolist-backend/middleware"
 	"github.com/MashuNakamura/todolist-backend/routes"
+	"github.com/MashuNakamura/todolist-backend/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/joho/godotenv"
 )
 
+const (
+	multipartOverhead = 1 * 1024 * 1024 // Boundary, header part & field lain di form upload
+	minBodyLimit      = 8 * 1024 * 1024
+)
+
 func main() {
 	// 1. Load .env
 	if err := godotenv.Load(); err != nil {
@@ -21,8 +32,45 @@ func main() {
 	// 2. Konek Database & Migrasi
 	config.ConnectDB()
 
-	// 3. Init Fiber
-	app := fiber.New()
+	// Load Key Signing JWT (gagal di awal kalau key tidak valid)
+	if err := helper.LoadSigningKeys(); err != nil {
+		log.Fatal("Failed to load JWT signing keys: ", err)
+	}
+	if err := middleware.LoadOAuthStateSecret(); err != nil {
+		log.Fatal("Failed to load OAuth state secret: ", err)
+	}
+	if err := middleware.LoadOAuthProviders(); err != nil {
+		log.Fatal("Failed to load OAuth providers: ", err)
+	}
+
+	// Storage untuk file upload (local / s3)
+	if err := storage.Init(); err != nil {
+		log.Fatal("Failed to init storage: ", err)
+	}
+
+	// Promote akun di ADMIN_EMAILS jadi admin
+	if err := helper.PromoteAdmins(); err != nil {
+		log.Println("Failed to promote admins:", err)
+	}
+
+	// 3. Background Jobs
+	jobs.StartTrashPurger(time.Hour)
+	jobs.StartReminderScheduler(time.Minute)
+	jobs.StartRateLimitCleanup(10*time.Minute, time.Hour)
+	jobs.StartAccountDeletionPurger(time.Hour)
+
+	// 4. Init Fiber
+	// Di belakang proxy (Render / Koyeb) c.IP() diambil dari X-Forwarded-For, hanya dari proxy di TRUSTED_PROXIES
+	trustedProxies := middleware.TrustedProxies()
+	// Batas body mengikuti ATTACHMENT_MAX_MB + overhead multipart, minimal cukup untuk avatar (maks 5 MB)
+	bodyLimit := max(helper.MaxAttachmentSize()+multipartOverhead, minBodyLimit)
+	app := fiber.New(fiber.Config{
+		BodyLimit:               int(bodyLimit),
+		ProxyHeader:             middleware.ClientIPHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          trustedProxies,
+	})
+	app.Use(middleware.ResolveClientIP(trustedProxies))
 	app.Use(logger.New())
 
 	app.Use(cors.New(cors.Config{
@@ -34,7 +82,7 @@ func main() {
 
 	routes.SetupRoutes(app)
 
-	// 4. Run
+	// 5. Run
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
@@ -20,7 +20,7 @@ func ConnectDB() {
 	// Setup Environment Variable
 	if dsn == "" {
 		dsn = fmt.Sprintf(
-			"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Jakarta",
+			"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
 			os.Getenv("DB_HOST"),
 			os.Getenv("DB_USER"),
 			os.Getenv("DB_PASSWORD"),
@@ -42,12 +42,28 @@ func ConnectDB() {
 
 	log.Println("Running Migrations...")
 
+	// Akun yang sudah ada sebelum kolom email_verified dibuat dianggap sudah verified
+	backfillEmailVerified := DB.Migrator().HasTable(&models.User{}) && !DB.Migrator().HasColumn(&models.User{}, "email_verified")
+
 	// Migrate the every schema here to create the table
-	err = DB.AutoMigrate(&models.User{}, &models.Task{}, &models.Category{})
+	err = DB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}, &models.RecoveryCode{}, &models.TwoFactorChallenge{}, &models.EmailVerification{}, &models.Identity{}, &models.APIToken{}, &models.AccountDeletionCode{}, &models.EmailChange{}, &models.UserPreference{}, &models.OAuthLoginCode{}, &models.Task{}, &models.Subtask{}, &models.TaskReminder{}, &models.TaskAttachment{}, &models.TaskComment{}, &models.TaskActivity{}, &models.Category{})
 
 	if err != nil {
 		log.Fatal("Migration failed: ", err)
 	}
 
+	if backfillEmailVerified {
+		if err := DB.Unscoped().Model(&models.User{}).Where("1 = 1").UpdateColumn("email_verified", true).Error; err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+	}
+
+	// Activity dulu ikut terhapus (cascade) saat task dihapus permanen
+	if DB.Migrator().HasConstraint(&models.TaskActivity{}, "fk_task_activities_task") {
+		if err := DB.Migrator().DropConstraint(&models.TaskActivity{}, "fk_task_activities_task"); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+	}
+
 	log.Println("Migrations success! Tables created.")
 }
@@ -0,0 +1,448 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultAccountDeletionGraceDays = 14
+	accountDeletionCodeTTL          = 10 * time.Minute
+	accountDeletionCodeCooldown     = time.Minute
+	maxAccountDeletionCodeAttempts  = 5
+)
+
+// Masa tenggang hapus akun dari ACCOUNT_DELETION_GRACE_DAYS (default 14 hari)
+func accountDeletionGrace() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"))
+	if err != nil || days < 0 {
+		days = defaultAccountDeletionGraceDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Isi file export (JSON)
+type accountExport struct {
+	ExportedAt time.Time         `json:"exported_at"`
+	Profile    models.User       `json:"profile"`
+	Tasks      []models.Task     `json:"tasks"`
+	Categories []models.Category `json:"categories"`
+}
+
+// API Untuk Export Data Akun (?format=json default, atau ?format=csv untuk zip berisi CSV)
+func ExportAccount(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid format (json, csv)",
+			Error:   400,
+		})
+	}
+
+	export := accountExport{ExportedAt: time.Now()}
+	if err := config.DB.First(&export.Profile, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if err := config.DB.
+		Preload("Subtasks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC, id ASC")
+		}).
+		Where("user_id = ?", userID).Order("id ASC").Find(&export.Tasks).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to export tasks",
+			Error:   500,
+		})
+	}
+
+	if err := config.DB.Where("user_id = ?", userID).Order("id ASC").Find(&export.Categories).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to export categories",
+			Error:   500,
+		})
+	}
+
+	filename := "todolist-export-" + export.ExportedAt.Format("20060102")
+
+	var body []byte
+	var err error
+	if format == "csv" {
+		body, err = exportCSVArchive(export)
+		c.Set(fiber.HeaderContentType, "application/zip")
+		filename += ".zip"
+	} else {
+		body, err = json.MarshalIndent(export, "", "  ")
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		filename += ".json"
+	}
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to build export",
+			Error:   500,
+		})
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return c.Send(body)
+}
+
+// Zip berisi profile.csv, tasks.csv, subtasks.csv & categories.csv
+func exportCSVArchive(export accountExport) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	profile := export.Profile
+	files := []struct {
+		name string
+		rows [][]string
+	}{
+		{"profile.csv", [][]string{
+			{"id", "name", "email", "email_verified", "totp_enabled", "created_at"},
+			{
+				strconv.FormatUint(uint64(profile.ID), 10), profile.Name, profile.Email,
+				strconv.FormatBool(profile.EmailVerified), strconv.FormatBool(profile.TOTPEnabled),
+				profile.CreatedAt.Format(time.RFC3339),
+			},
+		}},
+		{"tasks.csv", [][]string{{
+			"id", "title", "short_desc", "long_desc", "priority", "status", "time", "due_date",
+			"tags", "recurrence", "progress", "created_at", "updated_at",
+		}}},
+		{"subtasks.csv", [][]string{{"id", "task_id", "title", "done", "position"}}},
+		{"categories.csv", [][]string{{"id", "name", "color", "created_at"}}},
+	}
+
+	for _, task := range export.Tasks {
+		files[1].rows = append(files[1].rows, []string{
+			strconv.FormatUint(uint64(task.ID), 10), task.Title, task.ShortDesc, task.LongDesc,
+			task.Priority, task.Status, task.Time, formatTime(task.DueDate),
+			strings.Join(task.Tags, ";"), task.Recurrence, strconv.Itoa(task.Progress),
+			task.CreatedAt.Format(time.RFC3339), task.UpdatedAt.Format(time.RFC3339),
+		})
+		for _, subtask := range task.Subtasks {
+			files[2].rows = append(files[2].rows, []string{
+				strconv.FormatUint(uint64(subtask.ID), 10), strconv.FormatUint(uint64(subtask.TaskID), 10),
+				subtask.Title, strconv.FormatBool(subtask.Done), strconv.Itoa(subtask.Position),
+			})
+		}
+	}
+
+	for _, category := range export.Categories {
+		files[3].rows = append(files[3].rows, []string{
+			strconv.FormatUint(uint64(category.ID), 10), category.Name, category.Color,
+			category.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := csv.NewWriter(w).WriteAll(file.rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// API Untuk Kirim OTP Konfirmasi Hapus Akun (untuk akun tanpa password)
+func SendAccountDeletionCode(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	var existing models.AccountDeletionCode
+	if err := config.DB.Where("user_id = ?", userID).First(&existing).Error; err == nil &&
+		time.Since(existing.SentAt) < accountDeletionCodeCooldown {
+		return c.Status(429).JSON(models.Ret{
+			Success: false,
+			Message: "Please wait before requesting another OTP",
+			Error:   429,
+		})
+	}
+
+	otp := helper.GenerateOTP()
+	otpHash, err := helper.HashOTP(otp)
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate OTP",
+			Error:   500,
+		})
+	}
+
+	code := models.AccountDeletionCode{
+		UserID:    userID,
+		CodeHash:  otpHash,
+		ExpiresAt: time.Now().Add(accountDeletionCodeTTL),
+		SentAt:    time.Now(),
+	}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"code_hash", "expires_at", "attempts", "sent_at", "updated_at"}),
+	}).Create(&code).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate OTP",
+			Error:   500,
+		})
+	}
+
+	emailBody := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<body style="font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px;">
+		<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; padding: 20px; border-radius: 8px; text-align: center;">
+			<h2 style="color: #c0392b;">Confirm Account Deletion</h2>
+			<p>Hello %s,</p>
+			<p>We received a request to delete your account. Enter this code in the app to confirm:</p>
+			<div style="font-size: 32px; font-weight: bold; color: #2c3e50; letter-spacing: 5px;">%s</div>
+			<p>This code is valid for 10 minutes.</p>
+			<p>If you did not request this, please change your password immediately.</p>
+			<p style="font-size: 12px; color: #7f8c8d;">&copy; %d Koto Todolist. All rights reserved.</p>
+		</div>
+	</body>
+	</html>
+	`, html.EscapeString(user.Name), otp, time.Now().Year())
+
+	if err := helper.SendEmail(user.Email, "Confirm Account Deletion", emailBody); err != nil {
+		fmt.Println("Error sending email:", err)
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to send email",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "OTP has been sent to your email",
+		Error:   200,
+	})
+}
+
+// Cek OTP hapus akun, OTP hangus setelah dipakai atau terlalu banyak percobaan
+func checkAccountDeletionCode(userID uint, otp string) bool {
+	var code models.AccountDeletionCode
+	if err := config.DB.Where("user_id = ?", userID).First(&code).Error; err != nil {
+		return false
+	}
+
+	if time.Now().After(code.ExpiresAt) {
+		return false
+	}
+
+	// Percobaan dipesan dulu secara atomik supaya request paralel tidak bisa melewati batas
+	reserved := config.DB.Model(&models.AccountDeletionCode{}).
+		Where("id = ? AND attempts < ?", code.ID, maxAccountDeletionCodeAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if reserved.Error != nil || reserved.RowsAffected == 0 {
+		return false
+	}
+
+	if !helper.CheckOTP(code.CodeHash, otp) {
+		return false
+	}
+
+	return config.DB.Unscoped().Delete(&code).RowsAffected > 0
+}
+
+// API Untuk Hapus Akun (dijadwalkan, data dihapus permanen setelah masa tenggang)
+func DeleteAccount(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var input models.DeleteAccount
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Account deletion is already scheduled",
+			Error:   400,
+		})
+	}
+
+	switch {
+	case input.Password != "" && user.Password != "":
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid password",
+				Error:   400,
+			})
+		}
+	case input.OTP != "":
+		if !checkAccountDeletionCode(userID, input.OTP) {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid or expired OTP",
+				Error:   400,
+			})
+		}
+	default:
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Password or OTP is required",
+			Error:   400,
+		})
+	}
+
+	scheduledAt := time.Now().Add(accountDeletionGrace())
+	if err := config.DB.Model(&user).Update("deletion_scheduled_at", scheduledAt).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to schedule account deletion",
+			Error:   500,
+		})
+	}
+
+	emailBody := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<body style="font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px;">
+		<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; padding: 20px; border-radius: 8px; text-align: center;">
+			<h2 style="color: #c0392b;">Account Deletion Scheduled</h2>
+			<p>Hello %s,</p>
+			<p>Your account and all of its tasks and categories will be permanently deleted on <b>%s</b>.</p>
+			<p>Changed your mind? Sign in before then and cancel the deletion from your profile.</p>
+			<p style="font-size: 12px; color: #7f8c8d;">&copy; %d Koto Todolist. All rights reserved.</p>
+		</div>
+	</body>
+	</html>
+	`, html.EscapeString(user.Name), scheduledAt.Format("2 January 2006 15:04 MST"), time.Now().Year())
+
+	if err := helper.SendEmail(user.Email, "Account Deletion Scheduled", emailBody); err != nil {
+		fmt.Println("Error sending email:", err)
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Account deletion has been scheduled",
+		Error:   200,
+		Data: fiber.Map{
+			"deletion_scheduled_at": scheduledAt,
+		},
+	})
+}
+
+// API Untuk Batal Hapus Akun (selama masa tenggang)
+func CancelAccountDeletion(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	result := config.DB.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userID).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to cancel account deletion",
+			Error:   500,
+		})
+	}
+
+	if result.RowsAffected == 0 {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Account deletion is not scheduled",
+			Error:   400,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Account deletion has been cancelled",
+		Error:   200,
+	})
+}
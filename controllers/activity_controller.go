@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 100
+)
+
+// Parse & normalisasi page/limit activity
+func parseActivityQuery(c *fiber.Ctx) (models.ActivityQuery, error) {
+	var q models.ActivityQuery
+	if err := c.QueryParser(&q); err != nil {
+		return q, err
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 {
+		q.Limit = defaultActivityLimit
+	}
+	if q.Limit > maxActivityLimit {
+		q.Limit = maxActivityLimit
+	}
+	return q, nil
+}
+
+// Ambil satu halaman activity (terbaru dulu) beserta pagination meta
+func findActivities(query *gorm.DB, q models.ActivityQuery) ([]models.TaskActivity, models.Pagination, error) {
+	// Session baru supaya query bisa dipakai ulang untuk Count & Find
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	activities := []models.TaskActivity{}
+	if err := query.
+		Order("created_at DESC").
+		Order("id DESC").
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Find(&activities).Error; err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	return activities, models.Pagination{
+		Page:       q.Page,
+		Limit:      q.Limit,
+		Total:      total,
+		TotalPages: int((total + int64(q.Limit) - 1) / int64(q.Limit)),
+	}, nil
+}
+
+// API Untuk Timeline Activity dari Task (task di trash tetap bisa dilihat)
+func GetTaskActivity(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	q, err := parseActivityQuery(c)
+	if err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid query parameters",
+			Error:   400,
+		})
+	}
+
+	var task models.Task
+	if err := config.DB.Unscoped().Where("id = ? AND user_id = ?", c.Params("id"), userID).First(&task).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+
+	activities, meta, err := findActivities(config.DB.Model(&models.TaskActivity{}).Where("task_id = ?", task.ID), q)
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get task activity",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Task activity retrieved successfully",
+		Error:   200,
+		Data:    activities,
+		Meta:    meta,
+	})
+}
+
+// API Untuk Feed Activity Semua Task milik User
+func GetActivityFeed(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	q, err := parseActivityQuery(c)
+	if err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid query parameters",
+			Error:   400,
+		})
+	}
+
+	activities, meta, err := findActivities(config.DB.Model(&models.TaskActivity{}).Where("user_id = ?", userID), q)
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get activity feed",
+			Error:   500,
+		})
+	}
+
+	// Judul task untuk ditampilkan di feed (termasuk task yang sudah di trash)
+	if len(activities) > 0 {
+		ids := make([]uint, 0, len(activities))
+		for _, activity := range activities {
+			ids = append(ids, activity.TaskID)
+		}
+
+		var tasks []models.Task
+		config.DB.Unscoped().Select("id, title").Where("id IN ? AND user_id = ?", ids, userID).Find(&tasks)
+
+		titles := make(map[uint]string, len(tasks))
+		for _, task := range tasks {
+			titles[task.ID] = task.Title
+		}
+		for i := range activities {
+			activities[i].TaskTitle = titles[activities[i].TaskID]
+		}
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Activity feed retrieved successfully",
+		Error:   200,
+		Data:    activities,
+		Meta:    meta,
+	})
+}
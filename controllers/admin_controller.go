@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAdminUserLimit = 20
+	maxAdminUserLimit     = 100
+)
+
+// Kolom user + jumlah task (task yang sudah di trash tidak dihitung)
+const adminUserColumns = `users.id, users.name, users.email, users.role, users.email_verified,
+	users.totp_enabled, users.disabled_at, users.password_reset_required, users.created_at,
+	(SELECT COUNT(*) FROM tasks WHERE tasks.user_id = users.id AND tasks.deleted_at IS NULL) AS task_count,
+	(SELECT COUNT(*) FROM tasks WHERE tasks.user_id = users.id AND tasks.deleted_at IS NULL AND tasks.status <> 'done') AS open_task_count,
+	(SELECT COUNT(*) FROM tasks WHERE tasks.user_id = users.id AND tasks.deleted_at IS NULL AND tasks.status = 'done') AS done_task_count`
+
+func findAdminUser(id string) (models.AdminUser, error) {
+	var user models.AdminUser
+	err := config.DB.Model(&models.User{}).Select(adminUserColumns).Where("users.id = ?", id).Take(&user).Error
+	return user, err
+}
+
+// API Admin Untuk List & Search User
+func AdminGetUsers(c *fiber.Ctx) error {
+	var q models.AdminUserQuery
+	if err := c.QueryParser(&q); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid query parameters",
+			Error:   400,
+		})
+	}
+
+	query := config.DB.Model(&models.User{})
+
+	if q.Search != "" {
+		search := "%" + strings.ToLower(q.Search) + "%"
+		query = query.Where("(LOWER(users.name) LIKE ? OR LOWER(users.email) LIKE ?)", search, search)
+	}
+
+	if q.Role != "" {
+		if !helper.ValidRoles[q.Role] {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid role (user, admin)",
+				Error:   400,
+			})
+		}
+		query = query.Where("users.role = ?", q.Role)
+	}
+
+	switch q.Status {
+	case "":
+	case "active":
+		query = query.Where("users.disabled_at IS NULL")
+	case "disabled":
+		query = query.Where("users.disabled_at IS NOT NULL")
+	default:
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid status (active, disabled)",
+			Error:   400,
+		})
+	}
+
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 {
+		q.Limit = defaultAdminUserLimit
+	}
+	if q.Limit > maxAdminUserLimit {
+		q.Limit = maxAdminUserLimit
+	}
+
+	// Session baru supaya query bisa dipakai ulang untuk Count & Find
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get users",
+			Error:   500,
+		})
+	}
+
+	var users []models.AdminUser
+	if err := query.
+		Select(adminUserColumns).
+		Order("users.id ASC").
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Scan(&users).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get users",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Users retrieved successfully",
+		Error:   200,
+		Data:    users,
+		Meta: models.Pagination{
+			Page:       q.Page,
+			Limit:      q.Limit,
+			Total:      total,
+			TotalPages: int((total + int64(q.Limit) - 1) / int64(q.Limit)),
+		},
+	})
+}
+
+// API Admin Untuk Detail User
+func AdminGetUser(c *fiber.Ctx) error {
+	user, err := findAdminUser(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "User retrieved successfully",
+		Error:   200,
+		Data:    user,
+	})
+}
+
+// Update akun user oleh admin (tidak boleh ke akun sendiri) lalu cabut semua login-nya
+func adminModerateUser(c *fiber.Ctx, revoke bool, updates map[string]any, message string) error {
+	adminID, _ := c.Locals("user_id").(uint)
+
+	var user models.User
+	if err := config.DB.First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if user.ID == adminID {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "You cannot moderate your own account",
+			Error:   400,
+		})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		if revoke {
+			return helper.RevokeUserCredentials(tx, user.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to update user",
+			Error:   500,
+		})
+	}
+
+	result, _ := findAdminUser(c.Params("id"))
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: message,
+		Error:   200,
+		Data:    result,
+	})
+}
+
+// API Admin Untuk Disable Akun (semua session & API token dicabut)
+func AdminDisableUser(c *fiber.Ctx) error {
+	return adminModerateUser(c, true, map[string]any{"disabled_at": time.Now()}, "User disabled successfully")
+}
+
+// API Admin Untuk Enable Akun
+func AdminEnableUser(c *fiber.Ctx) error {
+	return adminModerateUser(c, false, map[string]any{"disabled_at": nil}, "User enabled successfully")
+}
+
+// API Admin Untuk Paksa Reset Password (user harus lewat forgot password)
+func AdminForcePasswordReset(c *fiber.Ctx) error {
+	return adminModerateUser(c, true, map[string]any{"password_reset_required": true}, "User must reset password on next login")
+}
+
+// API Admin Untuk Update Role User
+func AdminUpdateUserRole(c *fiber.Ctx) error {
+	var input models.UpdateUserRole
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	if !helper.ValidRoles[input.Role] {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid role (user, admin)",
+			Error:   400,
+		})
+	}
+
+	return adminModerateUser(c, false, map[string]any{"role": input.Role}, "User role updated successfully")
+}
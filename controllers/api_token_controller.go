@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"slices"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+)
+
+const maxAPITokenExpiryDays = 365
+
+// API Untuk Create Personal Access Token
+func CreateAPIToken(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var input models.CreateAPIToken
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	if input.Name == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Token name is required",
+			Error:   400,
+		})
+	}
+
+	if len(input.Scopes) == 0 {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "At least one scope is required",
+			Error:   400,
+		})
+	}
+
+	for _, scope := range input.Scopes {
+		if !slices.Contains(helper.APITokenScopes, scope) {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid scope: " + scope,
+				Error:   400,
+			})
+		}
+	}
+
+	if input.ExpiresInDays < 0 || input.ExpiresInDays > maxAPITokenExpiryDays {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "expires_in_days must be between 0 (never) and 365",
+			Error:   400,
+		})
+	}
+
+	raw, prefix, err := helper.GenerateAPIToken()
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate token",
+			Error:   500,
+		})
+	}
+
+	apiToken := models.APIToken{
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    prefix,
+		TokenHash: helper.HashToken(raw),
+		Scopes:    pq.StringArray(input.Scopes),
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		apiToken.ExpiresAt = &expiresAt
+	}
+
+	if err := config.DB.Create(&apiToken).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to create token",
+			Error:   500,
+		})
+	}
+
+	// Token mentah hanya ditampilkan sekali
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Token created successfully. Copy it now, it will not be shown again",
+		Error:   200,
+		Data: fiber.Map{
+			"token":   raw,
+			"details": apiToken,
+		},
+	})
+}
+
+// API Untuk List Personal Access Tokens
+func GetAPITokens(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var tokens []models.APIToken
+	if err := config.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get tokens",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Tokens retrieved successfully",
+		Error:   200,
+		Data:    tokens,
+	})
+}
+
+// API Untuk Revoke Personal Access Token
+func RevokeAPIToken(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	result := config.DB.Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Params("id"), userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to revoke token",
+			Error:   500,
+		})
+	}
+
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Token not found",
+			Error:   404,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Token revoked successfully",
+		Error:   200,
+	})
+}
@@ -0,0 +1,358 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/MashuNakamura/todolist-backend/storage"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxAttachmentsPerTask = 20
+
+var errAttachmentQuota = errors.New("attachment quota exceeded")
+
+// Nama file aman untuk disimpan & dipakai di Content-Disposition
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	if len(name) > 255 {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:255-len(ext)], "") + ext
+	}
+	return name
+}
+
+// Content type dari isi file, fallback ke ekstensi kalau tidak dikenali
+func detectContentType(head []byte, fileName string) string {
+	contentType := http.DetectContentType(head)
+	if contentType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(filepath.Ext(fileName)); byExt != "" {
+			return byExt
+		}
+	}
+	return contentType
+}
+
+// Total ukuran attachment milik user
+func attachmentUsage(db *gorm.DB, userID uint) (int64, error) {
+	var used int64
+	err := db.Model(&models.TaskAttachment{}).Where("user_id = ?", userID).
+		Select("COALESCE(SUM(size), 0)").Scan(&used).Error
+	return used, err
+}
+
+// API Untuk List Attachment dari Task
+func GetAttachments(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found",
+			Error:   404,
+		})
+	}
+
+	var attachments []models.TaskAttachment
+	if err := config.DB.Where("task_id = ?", task.ID).Order("created_at ASC, id ASC").Find(&attachments).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get attachments",
+			Error:   500,
+		})
+	}
+
+	used, _ := attachmentUsage(config.DB, userID)
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Attachments retrieved successfully",
+		Error:   200,
+		Data:    attachments,
+		Meta: fiber.Map{
+			"used_bytes":  used,
+			"quota_bytes": helper.AttachmentQuota(),
+		},
+	})
+}
+
+// API Untuk Upload Attachment ke Task (multipart, field "file")
+func UploadAttachment(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found",
+			Error:   404,
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "File is required (multipart field \"file\")",
+			Error:   400,
+		})
+	}
+
+	maxSize := helper.MaxAttachmentSize()
+	if fileHeader.Size <= 0 || fileHeader.Size > maxSize {
+		return c.Status(413).JSON(models.Ret{
+			Success: false,
+			Message: fmt.Sprintf("File must be between 1 byte and %d MB", maxSize/(1024*1024)),
+			Error:   413,
+		})
+	}
+
+	var count int64
+	config.DB.Model(&models.TaskAttachment{}).Where("task_id = ?", task.ID).Count(&count)
+	if count >= maxAttachmentsPerTask {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: fmt.Sprintf("Maximum %d attachments per task", maxAttachmentsPerTask),
+			Error:   400,
+		})
+	}
+
+	used, err := attachmentUsage(config.DB, userID)
+	if err == nil && used+fileHeader.Size > helper.AttachmentQuota() {
+		return c.Status(413).JSON(models.Ret{
+			Success: false,
+			Message: "Attachment storage quota exceeded",
+			Error:   413,
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to read file",
+			Error:   400,
+		})
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to read file",
+			Error:   400,
+		})
+	}
+
+	random, err := helper.RandomToken(16)
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to upload attachment",
+			Error:   500,
+		})
+	}
+
+	fileName := sanitizeFileName(fileHeader.Filename)
+	attachment := models.TaskAttachment{
+		TaskID:      task.ID,
+		UserID:      userID,
+		FileName:    fileName,
+		ContentType: detectContentType(head[:n], fileName),
+		Size:        fileHeader.Size,
+		StorageKey:  fmt.Sprintf("attachments/%d/%d/%s", userID, task.ID, random),
+	}
+
+	if err := storage.Default().Put(c.Context(), attachment.StorageKey, file, attachment.Size, attachment.ContentType); err != nil {
+		fmt.Println("Error storing attachment:", err)
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to upload attachment",
+			Error:   500,
+		})
+	}
+
+	// Cek ulang quota dengan lock di row user supaya upload paralel tidak bisa melewati batas
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+
+		used, err := attachmentUsage(tx, userID)
+		if err != nil {
+			return err
+		}
+		if used+attachment.Size > helper.AttachmentQuota() {
+			return errAttachmentQuota
+		}
+
+		return tx.Create(&attachment).Error
+	})
+	if err != nil {
+		helper.DeleteAttachmentObjects([]string{attachment.StorageKey})
+
+		if errors.Is(err, errAttachmentQuota) {
+			return c.Status(413).JSON(models.Ret{
+				Success: false,
+				Message: "Attachment storage quota exceeded",
+				Error:   413,
+			})
+		}
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to upload attachment",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Attachment uploaded successfully",
+		Error:   200,
+		Data:    attachment,
+	})
+}
+
+// API Untuk Download Attachment
+func DownloadAttachment(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found",
+			Error:   404,
+		})
+	}
+
+	var attachment models.TaskAttachment
+	if err := config.DB.Where("id = ? AND task_id = ?", c.Params("attachmentId"), task.ID).First(&attachment).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Attachment not found",
+			Error:   404,
+		})
+	}
+
+	reader, err := storage.Default().Get(c.Context(), attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.Status(404).JSON(models.Ret{
+				Success: false,
+				Message: "Attachment file not found",
+				Error:   404,
+			})
+		}
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to download attachment",
+			Error:   500,
+		})
+	}
+
+	// Selalu sebagai download (bukan inline) supaya file HTML/SVG tidak dieksekusi browser
+	c.Set(fiber.HeaderContentType, attachment.ContentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.SendStream(reader, int(attachment.Size))
+}
+
+// API Untuk Delete Attachment
+func DeleteAttachment(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found",
+			Error:   404,
+		})
+	}
+
+	var attachment models.TaskAttachment
+	if err := config.DB.Where("id = ? AND task_id = ?", c.Params("attachmentId"), task.ID).First(&attachment).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Attachment not found",
+			Error:   404,
+		})
+	}
+
+	if err := config.DB.Delete(&attachment).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to delete attachment",
+			Error:   500,
+		})
+	}
+
+	helper.DeleteAttachmentObjects([]string{attachment.StorageKey})
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Attachment deleted successfully",
+		Error:   200,
+	})
+}
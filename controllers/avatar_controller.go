@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/MashuNakamura/todolist-backend/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	maxAvatarBytes     = 5 * 1024 * 1024
+	avatarJPEGQuality  = 85
+	avatarCacheControl = "public, max-age=31536000, immutable"
+)
+
+// Versi avatar dari helper.RandomToken (base64url)
+var avatarVersionPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// API Untuk Upload Foto Profil (multipart, field "avatar")
+func UploadAvatar(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Avatar file is required (multipart field \"avatar\")",
+			Error:   400,
+		})
+	}
+
+	if fileHeader.Size > maxAvatarBytes {
+		return c.Status(413).JSON(models.Ret{
+			Success: false,
+			Message: "Avatar must be at most 5 MB",
+			Error:   413,
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to read avatar file",
+			Error:   400,
+		})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarBytes+1))
+	if err != nil || len(data) > maxAvatarBytes {
+		return c.Status(413).JSON(models.Ret{
+			Success: false,
+			Message: "Avatar must be at most 5 MB",
+			Error:   413,
+		})
+	}
+
+	img, err := helper.DecodeImage(data)
+	if err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid avatar: " + err.Error(),
+			Error:   400,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	version, err := helper.RandomToken(12)
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to upload avatar",
+			Error:   500,
+		})
+	}
+
+	// Versi baru setiap upload, jadi URL lama bisa di-cache selamanya
+	prefix := fmt.Sprintf("avatars/%d/%s", userID, version)
+	store := storage.Default()
+	for _, size := range models.AvatarSizes {
+		thumbnail, err := helper.EncodeJPEG(helper.SquareThumbnail(img, size), avatarJPEGQuality)
+		if err == nil {
+			err = store.Put(c.Context(), models.AvatarObjectKey(prefix, size), bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg")
+		}
+		if err != nil {
+			fmt.Println("Error storing avatar:", err)
+			helper.DeleteAvatarObjects(prefix)
+			return c.Status(500).JSON(models.Ret{
+				Success: false,
+				Message: "Failed to upload avatar",
+				Error:   500,
+			})
+		}
+	}
+
+	oldPrefix := user.AvatarKey
+	if err := config.DB.Model(&user).Update("avatar_key", prefix).Error; err != nil {
+		helper.DeleteAvatarObjects(prefix)
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to upload avatar",
+			Error:   500,
+		})
+	}
+
+	if err := helper.DeleteAvatarObjects(oldPrefix); err != nil {
+		fmt.Println("Error deleting old avatar:", err)
+	}
+
+	user.AfterFind(config.DB)
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Avatar uploaded successfully",
+		Error:   200,
+		Data: fiber.Map{
+			"avatar_url":        user.AvatarURL,
+			"avatar_thumbnails": user.AvatarThumbnails,
+		},
+	})
+}
+
+// API Untuk Hapus Foto Profil
+func DeleteAvatar(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if user.AvatarKey == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "No avatar to delete",
+			Error:   400,
+		})
+	}
+
+	if err := config.DB.Model(&user).Update("avatar_key", "").Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to delete avatar",
+			Error:   500,
+		})
+	}
+
+	if err := helper.DeleteAvatarObjects(user.AvatarKey); err != nil {
+		fmt.Println("Error deleting avatar:", err)
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Avatar deleted successfully",
+		Error:   200,
+	})
+}
+
+// API Public Untuk Ambil Gambar Avatar (/api/avatars/:userId/:version/:size.jpg)
+func GetAvatar(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	version := c.Params("version")
+	size, sizeErr := strconv.Atoi(strings.TrimSuffix(c.Params("file"), ".jpg"))
+	if err != nil || sizeErr != nil || !avatarVersionPattern.MatchString(version) || !strings.HasSuffix(c.Params("file"), ".jpg") {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Avatar not found",
+			Error:   404,
+		})
+	}
+
+	if !slices.Contains(models.AvatarSizes, size) {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Avatar not found",
+			Error:   404,
+		})
+	}
+
+	key := models.AvatarObjectKey(fmt.Sprintf("avatars/%d/%s", userID, version), size)
+	reader, err := storage.Default().Get(c.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.Status(404).JSON(models.Ret{
+				Success: false,
+				Message: "Avatar not found",
+				Error:   404,
+			})
+		}
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to load avatar",
+			Error:   500,
+		})
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to load avatar",
+			Error:   500,
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "image/jpeg")
+	c.Set(fiber.HeaderCacheControl, avatarCacheControl)
+	return c.Send(data)
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxCommentLength = 5000
+
+// Isi Author dari setiap comment (satu query untuk semua author)
+func attachCommentAuthors(comments []models.TaskComment) {
+	if len(comments) == 0 {
+		return
+	}
+
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.UserID)
+	}
+
+	var users []models.User
+	config.DB.Select("id, name, avatar_key").Where("id IN ?", ids).Find(&users)
+
+	authors := make(map[uint]*models.CommentAuthor, len(users))
+	for _, user := range users {
+		authors[user.ID] = &models.CommentAuthor{ID: user.ID, Name: user.Name, AvatarURL: user.AvatarURL}
+	}
+	for i := range comments {
+		comments[i].Author = authors[comments[i].UserID]
+	}
+}
+
+// Isi CommentCount dari task di list (satu query GROUP BY)
+func attachCommentCounts(tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	var rows []struct {
+		TaskID uint
+		Count  int64
+	}
+	if err := config.DB.Model(&models.TaskComment{}).
+		Select("task_id, COUNT(*) AS count").
+		Where("task_id IN ?", ids).
+		Group("task_id").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TaskID] = row.Count
+	}
+	for i := range tasks {
+		tasks[i].CommentCount = counts[tasks[i].ID]
+	}
+	return nil
+}
+
+// Validasi body comment, kembalikan pesan error (kosong = valid)
+func validateCommentBody(body string) string {
+	if body == "" {
+		return "Comment body is required"
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "Comment must be at most 5000 characters"
+	}
+	return ""
+}
+
+// API Untuk Get All Comments dari Task
+func GetComments(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+
+	var comments []models.TaskComment
+	if err := config.DB.Where("task_id = ?", task.ID).Order("created_at ASC, id ASC").Find(&comments).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get comments",
+			Error:   500,
+		})
+	}
+	attachCommentAuthors(comments)
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Comments retrieved successfully",
+		Error:   200,
+		Data:    comments,
+	})
+}
+
+// API Untuk Create Comment
+func CreateComment(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+
+	var input models.UpdateComment
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to parse request body",
+			Error:   400,
+		})
+	}
+
+	input.Body = strings.TrimSpace(input.Body)
+	if msg := validateCommentBody(input.Body); msg != "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: msg,
+			Error:   400,
+		})
+	}
+
+	comment := models.TaskComment{
+		TaskID: task.ID,
+		UserID: userID,
+		Body:   input.Body,
+	}
+	if err := config.DB.Create(&comment).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to create comment",
+			Error:   500,
+		})
+	}
+
+	comments := []models.TaskComment{comment}
+	attachCommentAuthors(comments)
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Comment created successfully",
+		Error:   200,
+		Data:    comments[0],
+	})
+}
+
+// API Untuk Edit Comment (hanya author)
+func UpdateComment(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+
+	var comment models.TaskComment
+	if err := config.DB.Where("id = ? AND task_id = ? AND user_id = ?", c.Params("commentId"), task.ID, userID).First(&comment).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Comment not found",
+			Error:   404,
+		})
+	}
+
+	var input models.UpdateComment
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to parse request body",
+			Error:   400,
+		})
+	}
+
+	input.Body = strings.TrimSpace(input.Body)
+	if msg := validateCommentBody(input.Body); msg != "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: msg,
+			Error:   400,
+		})
+	}
+
+	// edited_at hanya berubah kalau isinya memang berubah
+	if input.Body != comment.Body {
+		now := time.Now()
+		if err := config.DB.Model(&comment).Updates(map[string]any{"body": input.Body, "edited_at": now}).Error; err != nil {
+			return c.Status(500).JSON(models.Ret{
+				Success: false,
+				Message: "Failed to update comment",
+				Error:   500,
+			})
+		}
+		comment.Body = input.Body
+		comment.EditedAt = &now
+	}
+
+	comments := []models.TaskComment{comment}
+	attachCommentAuthors(comments)
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Comment updated successfully",
+		Error:   200,
+		Data:    comments[0],
+	})
+}
+
+// API Untuk Delete Comment (soft delete, hanya author)
+func DeleteComment(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+
+	result := config.DB.Where("id = ? AND task_id = ? AND user_id = ?", c.Params("commentId"), task.ID, userID).Delete(&models.TaskComment{})
+	if result.Error != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to delete comment",
+			Error:   500,
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Comment not found",
+			Error:   404,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Comment deleted successfully",
+		Error:   200,
+	})
+}
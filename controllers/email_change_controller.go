@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	emailChangeTTL         = 30 * time.Minute
+	emailChangeCooldown    = time.Minute
+	maxEmailChangeAttempts = 5
+)
+
+var errEmailTaken = errors.New("email already in use")
+
+// Cek apakah email sudah dipakai akun lain
+func emailTaken(db *gorm.DB, email string, userID uint) bool {
+	var count int64
+	db.Model(&models.User{}).Where("LOWER(email) = LOWER(?) AND id <> ?", email, userID).Count(&count)
+	return count > 0
+}
+
+// Samarkan email untuk notifikasi, contoh: jo***@gmail.com
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return "***"
+	}
+	if len(local) > 2 {
+		local = local[:2]
+	}
+	return local + "***@" + domain
+}
+
+// API Untuk Request Ganti Email (kode dikirim ke email baru, notifikasi ke email lama)
+func RequestEmailChange(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var input models.ChangeEmail
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	input.NewEmail = strings.TrimSpace(input.NewEmail)
+	if !helper.IsValidEmail(input.NewEmail) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid email format",
+			Error:   400,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	// Akun dengan password wajib konfirmasi password
+	if user.Password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid password",
+				Error:   400,
+			})
+		}
+	}
+
+	if strings.EqualFold(user.Email, input.NewEmail) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "New email must be different from current email",
+			Error:   400,
+		})
+	}
+
+	if emailTaken(config.DB, input.NewEmail, userID) {
+		return c.Status(409).JSON(models.Ret{
+			Success: false,
+			Message: "Email already in use",
+			Error:   409,
+		})
+	}
+
+	var pending models.EmailChange
+	if err := config.DB.Where("user_id = ?", userID).First(&pending).Error; err == nil &&
+		time.Since(pending.SentAt) < emailChangeCooldown {
+		return c.Status(429).JSON(models.Ret{
+			Success: false,
+			Message: "Please wait before requesting another code",
+			Error:   429,
+		})
+	}
+
+	code := helper.GenerateOTP()
+	codeHash, err := helper.HashOTP(code)
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate code",
+			Error:   500,
+		})
+	}
+
+	change := models.EmailChange{
+		UserID:    userID,
+		NewEmail:  input.NewEmail,
+		CodeHash:  codeHash,
+		ExpiresAt: time.Now().Add(emailChangeTTL),
+		SentAt:    time.Now(),
+	}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"new_email", "code_hash", "expires_at", "attempts", "sent_at", "updated_at"}),
+	}).Create(&change).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to request email change",
+			Error:   500,
+		})
+	}
+
+	confirmBody := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<body style="font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px;">
+		<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; padding: 20px; border-radius: 8px; text-align: center;">
+			<h2 style="color: #2c3e50;">Confirm Your New Email</h2>
+			<p>Hello %s,</p>
+			<p>Enter this code in the app to use this address for your Koto Todolist account:</p>
+			<div style="font-size: 32px; font-weight: bold; color: #2c3e50; letter-spacing: 5px;">%s</div>
+			<p>This code is valid for 30 minutes.</p>
+			<p>If you did not request this, you can ignore this email.</p>
+			<p style="font-size: 12px; color: #7f8c8d;">&copy; %d Koto Todolist. All rights reserved.</p>
+		</div>
+	</body>
+	</html>
+	`, html.EscapeString(user.Name), code, time.Now().Year())
+
+	if err := helper.SendEmail(input.NewEmail, "Confirm Your New Email", confirmBody); err != nil {
+		fmt.Println("Error sending email:", err)
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to send email",
+			Error:   500,
+		})
+	}
+
+	noticeBody := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<body style="font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px;">
+		<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; padding: 20px; border-radius: 8px; text-align: center;">
+			<h2 style="color: #2c3e50;">Email Change Requested</h2>
+			<p>Hello %s,</p>
+			<p>A request was made to change the email of your account to <b>%s</b>.</p>
+			<p>The change only applies once it is confirmed from the new address.</p>
+			<p>If this was not you, change your password and review your active sessions immediately.</p>
+			<p style="font-size: 12px; color: #7f8c8d;">&copy; %d Koto Todolist. All rights reserved.</p>
+		</div>
+	</body>
+	</html>
+	`, html.EscapeString(user.Name), html.EscapeString(maskEmail(input.NewEmail)), time.Now().Year())
+
+	if err := helper.SendEmail(user.Email, "Email Change Requested", noticeBody); err != nil {
+		fmt.Println("Error sending email:", err)
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Confirmation code has been sent to your new email",
+		Error:   200,
+		Data: fiber.Map{
+			"new_email":  change.NewEmail,
+			"expires_at": change.ExpiresAt,
+		},
+	})
+}
+
+// API Untuk Konfirmasi Ganti Email
+func ConfirmEmailChange(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var input models.ConfirmEmailChange
+	if err := c.BodyParser(&input); err != nil || input.Code == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Code is required",
+			Error:   400,
+		})
+	}
+
+	var change models.EmailChange
+	if err := config.DB.Where("user_id = ?", userID).First(&change).Error; err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "No pending email change",
+			Error:   400,
+		})
+	}
+
+	if time.Now().After(change.ExpiresAt) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Code has expired, please request a new one",
+			Error:   400,
+		})
+	}
+
+	// Percobaan dipesan dulu secara atomik supaya request paralel tidak bisa melewati batas
+	reserved := config.DB.Model(&models.EmailChange{}).
+		Where("id = ? AND attempts < ?", change.ID, maxEmailChangeAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if reserved.Error != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to change email",
+			Error:   500,
+		})
+	}
+	if reserved.RowsAffected == 0 {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Too many attempts, please request a new code",
+			Error:   400,
+		})
+	}
+
+	if !helper.CheckOTP(change.CodeHash, input.Code) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid code",
+			Error:   400,
+		})
+	}
+
+	// Email baru sudah terbukti milik user, jadi sekalian dianggap verified
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if emailTaken(tx, change.NewEmail, userID) {
+			return errEmailTaken
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Updates(map[string]any{"email": change.NewEmail, "email_verified": true}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&change).Error
+	})
+	if err != nil {
+		// Unique index users.email tetap jadi penjaga terakhir kalau ada race
+		if errors.Is(err, errEmailTaken) || emailTaken(config.DB, change.NewEmail, userID) {
+			return c.Status(409).JSON(models.Ret{
+				Success: false,
+				Message: "Email already in use",
+				Error:   409,
+			})
+		}
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to change email",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Email changed successfully",
+		Error:   200,
+		Data: fiber.Map{
+			"email": change.NewEmail,
+		},
+	})
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errIdentityNotFound = errors.New("linked account not found")
+	errLastLoginMethod  = errors.New("last login method")
+)
+
+// API Untuk List Linked Identities
+func GetIdentities(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var identities []models.Identity
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get linked accounts",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Linked accounts retrieved successfully",
+		Error:   200,
+		Data:    identities,
+	})
+}
+
+// API Untuk Unlink Identity
+func UnlinkIdentity(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	// User di-lock supaya dua unlink paralel tidak menghapus semua cara login
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		var identity models.Identity
+		if err := tx.Where("id = ? AND user_id = ?", c.Params("id"), userID).First(&identity).Error; err != nil {
+			return errIdentityNotFound
+		}
+
+		// Jangan sampai user kehilangan semua cara untuk login
+		var linked int64
+		if err := tx.Model(&models.Identity{}).Where("user_id = ?", userID).Count(&linked).Error; err != nil {
+			return err
+		}
+		if user.Password == "" && linked <= 1 {
+			return errLastLoginMethod
+		}
+
+		return tx.Unscoped().Delete(&identity).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+	if errors.Is(err, errIdentityNotFound) {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Linked account not found",
+			Error:   404,
+		})
+	}
+	if errors.Is(err, errLastLoginMethod) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Set a password before unlinking your last linked account",
+			Error:   400,
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to unlink account",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Account unlinked successfully",
+		Error:   200,
+	})
+}
+
+// API Untuk Tukar Kode Login OAuth (dari redirect callback) dengan Token
+func ExchangeOAuthCode(c *fiber.Ctx) error {
+	var input models.OAuthCodeExchange
+	if err := c.BodyParser(&input); err != nil || input.Code == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Code is required",
+			Error:   400,
+		})
+	}
+
+	userID, err := helper.ConsumeOAuthLoginCode(input.Code)
+	if err != nil {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid or expired login code, please try logging in again",
+			Error:   401,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if err := helper.CheckAccountStatus(user); err != nil {
+		return accountStatusError(c, err)
+	}
+
+	if !user.EmailVerified && helper.EmailVerificationMode() == helper.EmailVerificationLogin {
+		return c.Status(403).JSON(models.Ret{
+			Success: false,
+			Message: "Please verify your email before logging in",
+			Error:   403,
+		})
+	}
+
+	tokens, err := helper.IssueTokenPair(user.ID, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate token",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Login successfully",
+		Error:   200,
+		Data:    loginData(user, tokens),
+	})
+}
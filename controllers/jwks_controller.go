@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// API Untuk JSON Web Key Set (public key verifikasi access token)
+func JWKS(c *fiber.Ctx) error {
+	jwks, err := helper.JWKS()
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to load signing keys",
+			Error:   500,
+		})
+	}
+
+	// Format standar JWKS, tidak dibungkus models.Ret supaya bisa dibaca library JWT
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(jwks)
+}
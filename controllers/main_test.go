@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+var connectOnce sync.Once
+
+// Test yang butuh database hanya jalan kalau TEST_DATABASE_URL di-set (Postgres khusus test)
+func setupTestDB(t *testing.T) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set, skipping database test")
+	}
+
+	connectOnce.Do(func() {
+		os.Setenv("DATABASE_URL", dsn)
+		config.ConnectDB()
+	})
+}
+
+// Buat user test baru, dihapus permanen beserta datanya setelah test selesai
+func createTestUser(t *testing.T) models.User {
+	t.Helper()
+
+	user := models.User{
+		Name:  "Test User",
+		Email: fmt.Sprintf("test-%d@example.com", time.Now().UnixNano()),
+	}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		var taskIDs []uint
+		config.DB.Unscoped().Model(&models.Task{}).Where("user_id = ?", user.ID).Pluck("id", &taskIDs)
+		if len(taskIDs) > 0 {
+			config.DB.Unscoped().Where("task_id IN ?", taskIDs).Delete(&models.TaskActivity{})
+			config.DB.Unscoped().Where("task_id IN ?", taskIDs).Delete(&models.Subtask{})
+		}
+		config.DB.Unscoped().Where("user_id = ?", user.ID).Delete(&models.TaskActivity{})
+		config.DB.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Task{})
+		config.DB.Unscoped().Delete(&models.User{}, user.ID)
+	})
+	return user
+}
+
+// App Fiber dengan user_id yang sudah di-set (pengganti middleware.Protected)
+func newTestApp(userID uint) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	})
+	return app
+}
+
+func doJSON(t *testing.T, app *fiber.App, method, path, body string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("%s %s: status %d: %s", method, path, resp.StatusCode, data)
+	}
+	return string(data)
+}
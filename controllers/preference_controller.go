@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+)
+
+// Format locale BCP 47 sederhana, contoh: en, id, en-US, pt-BR
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// API Untuk Read Preferences
+func GetPreferences(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Preferences retrieved successfully",
+		Error:   200,
+		Data:    helper.GetPreferences(userID),
+	})
+}
+
+// API Untuk Update Preferences
+func UpdatePreferences(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var input models.UpdatePreferences
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	pref := helper.GetPreferences(userID)
+
+	if input.TimeZone != nil {
+		// Hanya nama IANA (Asia/Jakarta, Europe/Berlin), bukan "Local"
+		if _, err := time.LoadLocation(*input.TimeZone); err != nil || *input.TimeZone == "" || *input.TimeZone == "Local" {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid time zone (use an IANA name such as Asia/Jakarta)",
+				Error:   400,
+			})
+		}
+		pref.TimeZone = *input.TimeZone
+	}
+
+	if input.Locale != nil {
+		if !localePattern.MatchString(*input.Locale) {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid locale (e.g. en, id, en-US)",
+				Error:   400,
+			})
+		}
+		pref.Locale = *input.Locale
+	}
+
+	if input.WeekStart != nil {
+		weekStart := strings.ToLower(*input.WeekStart)
+		if _, ok := helper.WeekStarts[weekStart]; !ok {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid week_start (sunday, monday, saturday)",
+				Error:   400,
+			})
+		}
+		pref.WeekStart = weekStart
+	}
+
+	if input.DefaultPriority != nil {
+		priority := strings.ToLower(*input.DefaultPriority)
+		if !validPriorities[priority] {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid default_priority (low, medium, high)",
+				Error:   400,
+			})
+		}
+		pref.DefaultPriority = priority
+	}
+
+	if input.DefaultStatus != nil {
+		if !validStatuses[*input.DefaultStatus] {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid default_status (todo, ongoing, done)",
+				Error:   400,
+			})
+		}
+		pref.DefaultStatus = *input.DefaultStatus
+	}
+
+	// Baris preferences baru dibuat saat pertama kali diubah
+	query := config.DB
+	if pref.ID == 0 {
+		query = query.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"time_zone", "locale", "week_start", "default_priority", "default_status", "updated_at"}),
+		})
+	}
+	if err := query.Save(&pref).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to update preferences",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Preferences updated successfully",
+		Error:   200,
+		Data:    pref,
+	})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// API Untuk List Active Sessions
+func GetSessions(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	currentID, _ := c.Locals("session_id").(string)
+
+	var sessions []models.Session
+	if err := config.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get sessions",
+			Error:   500,
+		})
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].FamilyID == currentID
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Sessions retrieved successfully",
+		Error:   200,
+		Data:    sessions,
+	})
+}
+
+// API Untuk Revoke One Session
+func RevokeSession(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var session models.Session
+	if err := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Params("id"), userID).First(&session).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Session not found",
+			Error:   404,
+		})
+	}
+
+	if err := helper.RevokeSession(session.FamilyID); err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to revoke session",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Session revoked successfully",
+		Error:   200,
+	})
+}
+
+// API Untuk Logout dari Semua Device Lain
+func RevokeOtherSessions(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	currentID, _ := c.Locals("session_id").(string)
+
+	var familyIDs []string
+	if err := config.DB.Model(&models.Session{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, currentID).
+		Pluck("family_id", &familyIDs).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to revoke sessions",
+			Error:   500,
+		})
+	}
+
+	for _, familyID := range familyIDs {
+		if err := helper.RevokeSession(familyID); err != nil {
+			return c.Status(500).JSON(models.Ret{
+				Success: false,
+				Message: "Failed to revoke sessions",
+				Error:   500,
+			})
+		}
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Other sessions revoked successfully",
+		Error:   200,
+		Data:    fiber.Map{"revoked": len(familyIDs)},
+	})
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Pastikan task milik user yang sedang login
+func findUserTask(taskID string, userID uint) (models.Task, error) {
+	var task models.Task
+	err := config.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error
+	return task, err
+}
+
+// API Untuk Get All Subtasks dari Task
+func GetSubtasks(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+
+	var subtasks []models.Subtask
+	if err := config.DB.Where("task_id = ?", task.ID).Order("position ASC, id ASC").Find(&subtasks).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get subtasks",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Subtasks retrieved successfully",
+		Error:   200,
+		Data:    subtasks,
+	})
+}
+
+// API Untuk Create Subtask
+func CreateSubtask(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+
+	var input models.UpdateSubtask
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to parse request body",
+			Error:   400,
+		})
+	}
+
+	if input.Title == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Subtask title is required",
+			Error:   400,
+		})
+	}
+
+	subtask := models.Subtask{
+		TaskID: task.ID,
+		Title:  input.Title,
+	}
+	if input.Done != nil {
+		subtask.Done = *input.Done
+	}
+
+	// Default position: taruh di paling bawah
+	if input.Position != nil {
+		subtask.Position = *input.Position
+	} else {
+		var maxPosition *int
+		config.DB.Model(&models.Subtask{}).Where("task_id = ?", task.ID).Select("MAX(position)").Scan(&maxPosition)
+		if maxPosition != nil {
+			subtask.Position = *maxPosition + 1
+		}
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&subtask).Error; err != nil {
+			return err
+		}
+		return helper.RecordTaskActivities(tx, []models.TaskActivity{helper.SubtaskEvent(subtask, userID, helper.ActivitySubtaskCreated)})
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to create subtask",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Subtask created successfully",
+		Error:   200,
+		Data:    subtask,
+	})
+}
+
+// API Untuk Update Subtask
+func UpdateSubtask(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+
+	var input models.UpdateSubtask
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to parse request body",
+			Error:   400,
+		})
+	}
+
+	// Subtask di-lock supaya old_value di activity akurat saat ada update paralel
+	var subtask models.Subtask
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND task_id = ?", c.Params("subtaskId"), task.ID).First(&subtask).Error; err != nil {
+			return err
+		}
+
+		before := subtask
+		if input.Title != "" {
+			subtask.Title = input.Title
+		}
+		if input.Done != nil {
+			subtask.Done = *input.Done
+		}
+		if input.Position != nil {
+			subtask.Position = *input.Position
+		}
+
+		if err := tx.Save(&subtask).Error; err != nil {
+			return err
+		}
+		return helper.RecordTaskActivities(tx, helper.SubtaskChanges(before, subtask, userID))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Subtask not found",
+			Error:   404,
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to update subtask",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Subtask updated successfully",
+		Error:   200,
+		Data:    subtask,
+	})
+}
+
+// API Untuk Delete Subtask
+func DeleteSubtask(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	task, err := findUserTask(c.Params("id"), userID)
+	if err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var subtask models.Subtask
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND task_id = ?", c.Params("subtaskId"), task.ID).First(&subtask).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&subtask).Error; err != nil {
+			return err
+		}
+		return helper.RecordTaskActivities(tx, []models.TaskActivity{helper.SubtaskEvent(subtask, userID, helper.ActivitySubtaskDeleted)})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Subtask not found",
+			Error:   404,
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to delete subtask",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Subtask deleted successfully",
+		Error:   200,
+	})
+}
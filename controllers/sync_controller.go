@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const syncCursorPrefix = "v1:"
+
+// updated_at diisi saat statement dijalankan, bukan saat commit. Transaksi yang
+// commit terlambat (atau instance dengan jam sedikit beda) bisa menulis
+// updated_at lebih kecil dari cursor yang sudah dikirim. Cursor dimundurkan
+// sebesar lag ini supaya perubahan tersebut ikut di sync berikutnya; client
+// menerima ulang sebagian record (upsert idempotent) tapi tidak ada yang hilang.
+const syncSafetyLag = 30 * time.Second
+
+// Cursor adalah timestamp (UnixNano) yang di-encode base64 supaya opaque untuk client
+func encodeSyncCursor(t time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(syncCursorPrefix + strconv.FormatInt(t.UnixNano(), 10)))
+}
+
+func decodeSyncCursor(cursor string) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	value, ok := strings.CutPrefix(string(raw), syncCursorPrefix)
+	if !ok {
+		return time.Time{}, errors.New("unknown cursor version")
+	}
+
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, nanos), nil
+}
+
+// Ambil record yang berubah (aktif) dan tombstone (soft-deleted) sejak cursor
+func syncChanges[T any](tx *gorm.DB, userID uint, since, until time.Time) ([]T, []models.Tombstone, error) {
+	var changed []T
+	query := tx.Where("user_id = ? AND updated_at <= ?", userID, until)
+	if !since.IsZero() {
+		query = query.Where("updated_at > ?", since)
+	}
+	if err := query.Find(&changed).Error; err != nil {
+		return nil, nil, err
+	}
+
+	deleted := []models.Tombstone{}
+	if since.IsZero() {
+		// Full sync: client belum punya data lokal, tidak perlu tombstone
+		return changed, deleted, nil
+	}
+
+	if err := tx.Unscoped().Model(new(T)).
+		Select("id, deleted_at").
+		Where("user_id = ? AND deleted_at > ? AND deleted_at <= ?", userID, since, until).
+		Scan(&deleted).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return changed, deleted, nil
+}
+
+// API Untuk Incremental Sync (Tasks & Categories)
+func Sync(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var since time.Time
+	if cursor := c.Query("cursor"); cursor != "" {
+		parsed, err := decodeSyncCursor(cursor)
+		if err != nil {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid cursor",
+				Error:   400,
+			})
+		}
+		since = parsed
+	}
+
+	until := time.Now()
+	cursor := until.Add(-syncSafetyLag)
+	if cursor.Before(since) {
+		cursor = since
+	}
+
+	var resp models.SyncResponse
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if resp.Tasks, resp.DeletedTasks, err = syncChanges[models.Task](tx, userID, since, until); err != nil {
+			return err
+		}
+		if resp.Categories, resp.DeletedCategories, err = syncChanges[models.Category](tx, userID, since, until); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to sync data",
+			Error:   500,
+		})
+	}
+
+	resp.Cursor = encodeSyncCursor(cursor)
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Sync successfully",
+		Error:   200,
+		Data:    resp,
+	})
+}
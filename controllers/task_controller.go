@@ -1,12 +1,19 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
 	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/MashuNakamura/todolist-backend/recurrence"
 	"github.com/gofiber/fiber/v2"
 	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // API Create Task
@@ -21,14 +28,15 @@ func CreateTask(c *fiber.Ctx) error {
 		})
 	}
 
-	var task models.Task
-	if err := c.BodyParser(&task); err != nil {
+	var input models.CreateTask
+	if err := c.BodyParser(&input); err != nil {
 		return c.Status(400).JSON(models.Ret{
 			Success: false,
 			Message: "Failed to parse request body",
 			Error:   400,
 		})
 	}
+	task := input.Task
 
 	if task.Title == "" {
 		return c.Status(400).JSON(models.Ret{
@@ -38,15 +46,56 @@ func CreateTask(c *fiber.Ctx) error {
 		})
 	}
 
+	pref := helper.GetPreferences(userID)
+
+	// Tanggal tanpa jam (2006-01-02) dianggap tengah malam di time zone user
+	if input.DueDate != "" {
+		parsedTime, err := helper.ParseDate(input.DueDate, helper.Location(pref))
+		if err != nil {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid due_date format",
+				Error:   400,
+			})
+		}
+		task.DueDate = &parsedTime
+	}
+
 	if task.Priority == "" {
-		task.Priority = "Medium"
+		task.Priority = pref.DefaultPriority
 	}
 	if task.Status == "" {
-		task.Status = "todo"
+		task.Status = pref.DefaultStatus
 	}
 	task.UserID = userID
 
-	if err := config.DB.Create(&task).Error; err != nil {
+	recurrenceRule, err := normalizeRecurrence(task.Recurrence, task.DueDate, helper.Location(pref))
+	if err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: err.Error(),
+			Error:   400,
+		})
+	}
+	task.Recurrence = recurrenceRule
+	task.Occurrence = 1
+	task.NextTaskID = nil
+
+	if err := validateReminderOffsets(task.ReminderOffsets); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: err.Error(),
+			Error:   400,
+		})
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		return helper.RecordTaskActivities(tx, []models.TaskActivity{helper.TaskEvent(task.ID, userID, helper.ActivityCreated)})
+	})
+	if err != nil {
 		return c.Status(500).JSON(models.Ret{
 			Success: false,
 			Message: "Failed to create task",
@@ -62,7 +111,26 @@ func CreateTask(c *fiber.Ctx) error {
 	})
 }
 
-// API Get All Tasks
+// Whitelist status & priority yang valid
+var validStatuses = map[string]bool{"todo": true, "ongoing": true, "done": true}
+var validPriorities = map[string]bool{"low": true, "medium": true, "high": true}
+
+// Whitelist kolom sort, supaya query param tidak bisa inject kolom sembarang
+var taskSortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"due_date":   "due_date",
+	"title":      "title",
+	"status":     "status",
+	"priority":   "CASE LOWER(priority) WHEN 'high' THEN 3 WHEN 'medium' THEN 2 WHEN 'low' THEN 1 ELSE 0 END",
+}
+
+const (
+	defaultTaskLimit = 50
+	maxTaskLimit     = 100
+)
+
+// API Get All Tasks (Filter, Sort & Pagination)
 func GetAllTasks(c *fiber.Ctx) error {
 	val := c.Locals("user_id")
 	userID, ok := val.(uint)
@@ -74,14 +142,156 @@ func GetAllTasks(c *fiber.Ctx) error {
 		})
 	}
 
+	var q models.TaskQuery
+	if err := c.QueryParser(&q); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid query parameters",
+			Error:   400,
+		})
+	}
+
+	query := config.DB.Model(&models.Task{}).Where("user_id = ?", userID)
+
+	// Tanggal tanpa jam, "hari ini" & "minggu ini" mengikuti time zone user
+	pref := helper.GetPreferences(userID)
+	loc := helper.Location(pref)
+	today := helper.StartOfDay(time.Now(), loc)
+
+	if q.Status != "" {
+		if !validStatuses[q.Status] {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid status (todo, ongoing, done)",
+				Error:   400,
+			})
+		}
+		query = query.Where("status = ?", q.Status)
+	}
+
+	if q.Priority != "" {
+		if !validPriorities[strings.ToLower(q.Priority)] {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid priority (low, medium, high)",
+				Error:   400,
+			})
+		}
+		query = query.Where("LOWER(priority) = ?", strings.ToLower(q.Priority))
+	}
+
+	if q.Tag != "" {
+		query = query.Where("? = ANY(tags)", q.Tag)
+	}
+
+	if q.DueBefore != "" {
+		dueBefore, err := helper.ParseDate(q.DueBefore, loc)
+		if err != nil {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid due_before format",
+				Error:   400,
+			})
+		}
+		query = query.Where("due_date < ?", dueBefore)
+	}
+
+	if q.DueAfter != "" {
+		dueAfter, err := helper.ParseDate(q.DueAfter, loc)
+		if err != nil {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid due_after format",
+				Error:   400,
+			})
+		}
+		query = query.Where("due_date > ?", dueAfter)
+	}
+
+	// Overdue = jatuh tempo sebelum hari ini, task yang due hari ini masih masuk "today"
+	if q.Overdue {
+		query = query.Where("due_date < ? AND status <> ?", today, "done")
+	}
+
+	if q.Today {
+		query = query.Where("due_date >= ? AND due_date < ?", today, today.AddDate(0, 0, 1))
+	}
+
+	if q.ThisWeek {
+		weekStart := helper.StartOfWeek(today, loc, pref.WeekStart)
+		query = query.Where("due_date >= ? AND due_date < ?", weekStart, weekStart.AddDate(0, 0, 7))
+	}
+
+	sortColumn := taskSortColumns["created_at"]
+	if q.Sort != "" {
+		column, ok := taskSortColumns[q.Sort]
+		if !ok {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid sort field (created_at, updated_at, due_date, title, status, priority)",
+				Error:   400,
+			})
+		}
+		sortColumn = column
+	}
+
+	order := "DESC"
+	switch strings.ToLower(q.Order) {
+	case "", "desc":
+	case "asc":
+		order = "ASC"
+	default:
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid order (asc, desc)",
+			Error:   400,
+		})
+	}
+
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 {
+		q.Limit = defaultTaskLimit
+	}
+	if q.Limit > maxTaskLimit {
+		q.Limit = maxTaskLimit
+	}
+
+	// Session baru supaya query bisa dipakai ulang untuk Count & Find
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get tasks",
+			Error:   500,
+		})
+	}
+
 	var tasks []models.Task
+	if err := query.
+		Preload("Subtasks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC, id ASC")
+		}).
+		Order(sortColumn + " " + order + " NULLS LAST").
+		Order("id " + order).
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Find(&tasks).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get tasks",
+			Error:   500,
+		})
+	}
 
-	if err := config.DB.Where("user_id = ?", userID).Find(&tasks).Error; err != nil {
-		return c.JSON(models.Ret{
+	if err := attachCommentCounts(tasks); err != nil {
+		return c.Status(500).JSON(models.Ret{
 			Success: false,
 			Message: "Failed to get tasks",
 			Error:   500,
-			Data:    nil,
 		})
 	}
 
@@ -90,6 +300,12 @@ func GetAllTasks(c *fiber.Ctx) error {
 		Message: "Tasks retrieved successfully",
 		Error:   200,
 		Data:    tasks,
+		Meta: models.Pagination{
+			Page:       q.Page,
+			Limit:      q.Limit,
+			Total:      total,
+			TotalPages: int((total + int64(q.Limit) - 1) / int64(q.Limit)),
+		},
 	})
 }
 
@@ -138,7 +354,9 @@ func GetTaskByID(c *fiber.Ctx) error {
 
 	var task models.Task
 
-	if err := config.DB.Where("id = ? AND user_id = ?", c.Params("id"), userID).First(&task).Error; err != nil {
+	if err := config.DB.Preload("Subtasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC, id ASC")
+	}).Where("id = ? AND user_id = ?", c.Params("id"), userID).First(&task).Error; err != nil {
 		return c.JSON(models.Ret{
 			Success: false,
 			Message: "Failed to get task",
@@ -147,6 +365,8 @@ func GetTaskByID(c *fiber.Ctx) error {
 		})
 	}
 
+	config.DB.Model(&models.TaskComment{}).Where("task_id = ?", task.ID).Count(&task.CommentCount)
+
 	return c.JSON(models.Ret{
 		Success: true,
 		Message: "Task retrieved successfully",
@@ -155,38 +375,9 @@ func GetTaskByID(c *fiber.Ctx) error {
 	})
 }
 
-// API Edit Task by ID
-func UpdateTask(c *fiber.Ctx) error {
-	val := c.Locals("user_id")
-	userID, ok := val.(uint)
-	if !ok {
-		return c.Status(401).JSON(models.Ret{
-			Success: false,
-			Message: "Unauthorized: Invalid User Session",
-			Error:   401,
-		})
-	}
-
-	id := c.Params("id")
-
-	var task models.Task
-	if err := config.DB.Where("id = ? AND user_id = ?", id, userID).First(&task).Error; err != nil {
-		return c.Status(404).JSON(models.Ret{
-			Success: false,
-			Message: "Task not found or access denied",
-			Error:   404,
-		})
-	}
-
-	var updateTask models.UpdateTask
-	if err := c.BodyParser(&updateTask); err != nil {
-		return c.Status(400).JSON(models.Ret{
-			Success: false,
-			Message: "Failed to parse request body",
-			Error:   400,
-		})
-	}
-
+// Terapkan field yang dikirim client ke task. Error yang dikembalikan adalah
+// kesalahan input dan pesannya langsung dikirim ke client.
+func applyTaskUpdate(task *models.Task, updateTask models.UpdateTask, loc *time.Location) error {
 	if updateTask.Title != "" {
 		task.Title = updateTask.Title
 	}
@@ -201,16 +392,10 @@ func UpdateTask(c *fiber.Ctx) error {
 	}
 
 	if updateTask.Status != "" {
-		validStatuses := map[string]bool{"todo": true, "ongoing": true, "done": true}
-		if validStatuses[updateTask.Status] {
-			task.Status = updateTask.Status
-		} else {
-			return c.Status(400).JSON(models.Ret{
-				Success: false,
-				Message: "Invalid status (todo, ongoing, done)",
-				Error:   400,
-			})
+		if !validStatuses[updateTask.Status] {
+			return errors.New("Invalid status (todo, ongoing, done)")
 		}
+		task.Status = updateTask.Status
 	}
 
 	if updateTask.Time != "" {
@@ -218,16 +403,9 @@ func UpdateTask(c *fiber.Ctx) error {
 	}
 
 	if updateTask.DueDate != "" {
-		parsedTime, err := time.Parse(time.RFC3339, updateTask.DueDate)
+		parsedTime, err := helper.ParseDate(updateTask.DueDate, loc)
 		if err != nil {
-			parsedTime, err = time.Parse("2006-01-02", updateTask.DueDate)
-			if err != nil {
-				return c.Status(400).JSON(models.Ret{
-					Success: false,
-					Message: "Invalid due_date format",
-					Error:   400,
-				})
-			}
+			return errors.New("Invalid due_date format")
 		}
 		task.DueDate = &parsedTime
 	}
@@ -236,9 +414,98 @@ func UpdateTask(c *fiber.Ctx) error {
 		task.Tags = pq.StringArray(updateTask.Tags)
 	}
 
-	task.UserID = userID
+	if updateTask.Recurrence != nil {
+		task.Recurrence = *updateTask.Recurrence
+	}
+	if updateTask.Recurrence != nil || (updateTask.DueDate != "" && task.Recurrence != "") {
+		recurrenceRule, err := normalizeRecurrence(task.Recurrence, task.DueDate, loc)
+		if err != nil {
+			return err
+		}
+		task.Recurrence = recurrenceRule
+	}
 
-	if err := config.DB.Save(&task).Error; err != nil {
+	if updateTask.ReminderOffsets != nil {
+		if err := validateReminderOffsets(updateTask.ReminderOffsets); err != nil {
+			return err
+		}
+		task.ReminderOffsets = pq.Int64Array(updateTask.ReminderOffsets)
+	}
+	return nil
+}
+
+// API Edit Task by ID
+func UpdateTask(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var updateTask models.UpdateTask
+	if err := c.BodyParser(&updateTask); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to parse request body",
+			Error:   400,
+		})
+	}
+
+	id := c.Params("id")
+	loc := helper.UserLocation(userID)
+
+	// Task dibaca dengan lock di dalam transaksi supaya update paralel tidak
+	// saling menimpa, old_value activity akurat, dan occurrence berikutnya
+	// hanya di-generate sekali
+	var task models.Task
+	var inputErr error
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).First(&task).Error; err != nil {
+			return err
+		}
+
+		before := task
+		wasDone := task.Status == "done"
+
+		if inputErr = applyTaskUpdate(&task, updateTask, loc); inputErr != nil {
+			return inputErr
+		}
+		task.UserID = userID
+
+		if err := tx.Save(&task).Error; err != nil {
+			return err
+		}
+
+		if err := helper.RecordTaskActivities(tx, helper.TaskChanges(before, task, userID)); err != nil {
+			return err
+		}
+
+		// Generate occurrence berikutnya saat recurring task selesai
+		if !wasDone && task.Status == "done" {
+			return spawnNextOccurrence(tx, &task)
+		}
+		return nil
+	})
+	if inputErr != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: inputErr.Error(),
+			Error:   400,
+		})
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "Task not found or access denied",
+			Error:   404,
+		})
+	}
+	if err != nil {
 		return c.Status(500).JSON(models.Ret{
 			Success: false,
 			Message: "Failed to update task",
@@ -284,7 +551,32 @@ func DeleteTask(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := config.DB.Where("id IN ? AND user_id = ?", req.IDs, userID).Delete(&models.Task{}).Error; err != nil {
+	// Soft-delete subtasks ikut bersama task induknya
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var taskIDs []uint
+		if err := tx.Model(&models.Task{}).Where("id IN ? AND user_id = ?", req.IDs, userID).Pluck("id", &taskIDs).Error; err != nil {
+			return err
+		}
+		if len(taskIDs) == 0 {
+			return nil
+		}
+
+		// deleted_at sama persis dengan task, dipakai RestoreTasks untuk mengenali subtask yang ikut terhapus
+		now := time.Now()
+		if err := tx.Model(&models.Subtask{}).Where("task_id IN ?", taskIDs).UpdateColumn("deleted_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Task{}).Where("id IN ?", taskIDs).UpdateColumn("deleted_at", now).Error; err != nil {
+			return err
+		}
+
+		activities := make([]models.TaskActivity, 0, len(taskIDs))
+		for _, taskID := range taskIDs {
+			activities = append(activities, helper.TaskEvent(taskID, userID, helper.ActivityDeleted))
+		}
+		return helper.RecordTaskActivities(tx, activities)
+	})
+	if err != nil {
 		return c.Status(500).JSON(models.Ret{
 			Success: false,
 			Message: "Failed to delete tasks",
@@ -313,7 +605,7 @@ func UpdateBatchStatus(c *fiber.Ctx) error {
 
 	var req models.UpdateBatchStatus
 	if err := c.BodyParser(&req); err != nil {
-		return c.JSON(models.Ret{
+		return c.Status(400).JSON(models.Ret{
 			Success: false,
 			Message: "Invalid data",
 			Error:   400,
@@ -321,15 +613,60 @@ func UpdateBatchStatus(c *fiber.Ctx) error {
 	}
 
 	if len(req.IDs) == 0 {
-		return c.JSON(models.Ret{
+		return c.Status(400).JSON(models.Ret{
 			Success: false,
 			Message: "No IDs provided",
 			Error:   400,
 		})
 	}
 
-	if err := config.DB.Model(&models.Task{}).Where("id IN ? AND user_id = ?", req.IDs, userID).Update("status", req.Status).Error; err != nil {
-		return c.JSON(models.Ret{
+	if !validStatuses[req.Status] {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid status (todo, ongoing, done)",
+			Error:   400,
+		})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Task yang status-nya berubah (di-lock supaya status lama yang dicatat akurat)
+		var changed []models.Task
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND user_id = ? AND status <> ?", req.IDs, userID, req.Status).
+			Find(&changed).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Task{}).Where("id IN ? AND user_id = ?", req.IDs, userID).Update("status", req.Status).Error; err != nil {
+			return err
+		}
+
+		activities := make([]models.TaskActivity, 0, len(changed))
+		for i := range changed {
+			before := changed[i]
+			changed[i].Status = req.Status
+			activities = append(activities, helper.TaskChanges(before, changed[i], userID)...)
+		}
+		if err := helper.RecordTaskActivities(tx, activities); err != nil {
+			return err
+		}
+
+		// Recurring task yang baru pindah ke done perlu generate occurrence berikutnya
+		if req.Status != "done" {
+			return nil
+		}
+		for i := range changed {
+			if changed[i].Recurrence == "" {
+				continue
+			}
+			if err := spawnNextOccurrence(tx, &changed[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
 			Success: false,
 			Message: "Failed to update tasks",
 			Error:   500,
@@ -342,3 +679,95 @@ func UpdateBatchStatus(c *fiber.Ctx) error {
 		Error:   200,
 	})
 }
+
+const (
+	maxReminderOffsets = 5
+	maxReminderOffset  = 30 * 24 * 60 // 30 hari dalam menit
+)
+
+// Validasi reminder offset (menit sebelum due date)
+func validateReminderOffsets(offsets []int64) error {
+	if len(offsets) > maxReminderOffsets {
+		return fmt.Errorf("Maximum %d reminders per task", maxReminderOffsets)
+	}
+	for _, offset := range offsets {
+		if offset < 0 || offset > maxReminderOffset {
+			return fmt.Errorf("Reminder offset must be between 0 and %d minutes", maxReminderOffset)
+		}
+	}
+	return nil
+}
+
+// Validasi recurrence rule dan kunci hari (BYDAY / BYMONTHDAY) dari due date di time zone user
+func normalizeRecurrence(value string, dueDate *time.Time, loc *time.Location) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	if dueDate == nil {
+		return "", errors.New("Recurring task requires a due_date")
+	}
+
+	rule, err := recurrence.Parse(value)
+	if err != nil {
+		return "", errors.New("Invalid recurrence rule")
+	}
+
+	return rule.Anchor(dueDate.In(loc)).String(), nil
+}
+
+// Buat task occurrence berikutnya dengan due date yang sudah dimajukan
+func spawnNextOccurrence(tx *gorm.DB, task *models.Task) error {
+	if task.Recurrence == "" || task.DueDate == nil || task.NextTaskID != nil {
+		return nil
+	}
+
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		return err
+	}
+
+	// Hitung di time zone user supaya jam & hari tetap sama walau ada DST
+	occurrence := max(task.Occurrence, 1)
+	nextDue, ok := rule.Next(task.DueDate.In(helper.UserLocation(task.UserID)), occurrence)
+	if !ok {
+		return nil
+	}
+
+	var subtasks []models.Subtask
+	if err := tx.Where("task_id = ?", task.ID).Order("position ASC, id ASC").Find(&subtasks).Error; err != nil {
+		return err
+	}
+
+	next := models.Task{
+		Title:      task.Title,
+		ShortDesc:  task.ShortDesc,
+		LongDesc:   task.LongDesc,
+		Priority:   task.Priority,
+		Status:     "todo",
+		Time:       task.Time,
+		DueDate:    &nextDue,
+		Tags:       task.Tags,
+		UserID:     task.UserID,
+		Recurrence: task.Recurrence,
+		Occurrence: occurrence + 1,
+
+		ReminderOffsets: task.ReminderOffsets,
+	}
+	for _, subtask := range subtasks {
+		next.Subtasks = append(next.Subtasks, models.Subtask{
+			Title:    subtask.Title,
+			Position: subtask.Position,
+		})
+	}
+
+	if err := tx.Create(&next).Error; err != nil {
+		return err
+	}
+	if err := helper.RecordTaskActivities(tx, []models.TaskActivity{helper.TaskEvent(next.ID, next.UserID, helper.ActivityCreated)}); err != nil {
+		return err
+	}
+
+	task.NextTaskID = &next.ID
+	return tx.Model(&models.Task{}).Where("id = ?", task.ID).Update("next_task_id", next.ID).Error
+}
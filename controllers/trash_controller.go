@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// API Untuk List Task di Trash
+func GetTrashedTasks(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var tasks []models.Task
+	if err := config.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Order("deleted_at DESC").Find(&tasks).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get trashed tasks",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Trashed tasks retrieved successfully",
+		Error:   200,
+		Data:    tasks,
+	})
+}
+
+// API Untuk Restore Task dari Trash (One or Many)
+func RestoreTasks(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var req models.TrashIDs
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid data",
+			Error:   400,
+		})
+	}
+
+	if len(req.IDs) == 0 {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "No IDs provided",
+			Error:   400,
+		})
+	}
+
+	// Restore subtasks yang ikut terhapus bersama task induknya (deleted_at sama dengan task),
+	// subtask yang sudah dihapus sendiri sebelumnya tetap di trash
+	var restored int64
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Subtask{}).
+			Where("deleted_at = (?)",
+				tx.Unscoped().Model(&models.Task{}).Select("deleted_at").
+					Where("tasks.id = subtasks.task_id AND tasks.id IN ? AND tasks.user_id = ? AND tasks.deleted_at IS NOT NULL", req.IDs, userID)).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		var taskIDs []uint
+		if err := tx.Unscoped().Model(&models.Task{}).
+			Where("id IN ? AND user_id = ? AND deleted_at IS NOT NULL", req.IDs, userID).
+			Pluck("id", &taskIDs).Error; err != nil {
+			return err
+		}
+		if len(taskIDs) == 0 {
+			return nil
+		}
+
+		result := tx.Unscoped().Model(&models.Task{}).Where("id IN ?", taskIDs).Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		restored = result.RowsAffected
+
+		activities := make([]models.TaskActivity, 0, len(taskIDs))
+		for _, taskID := range taskIDs {
+			activities = append(activities, helper.TaskEvent(taskID, userID, helper.ActivityRestored))
+		}
+		return helper.RecordTaskActivities(tx, activities)
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to restore tasks",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Tasks restored successfully",
+		Error:   200,
+		Data:    fiber.Map{"restored": restored},
+	})
+}
+
+// API Untuk Hapus Permanen Task dari Trash (One or Many)
+func PurgeTasks(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var req models.TrashIDs
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid data",
+			Error:   400,
+		})
+	}
+
+	if len(req.IDs) == 0 {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "No IDs provided",
+			Error:   400,
+		})
+	}
+
+	// Attachment ikut dihapus, file di storage dibersihkan setelah commit
+	var purged int64
+	var attachmentKeys []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var taskIDs []uint
+		if err := tx.Unscoped().Model(&models.Task{}).
+			Where("id IN ? AND user_id = ? AND deleted_at IS NOT NULL", req.IDs, userID).
+			Pluck("id", &taskIDs).Error; err != nil {
+			return err
+		}
+		if len(taskIDs) == 0 {
+			return nil
+		}
+
+		var err error
+		if attachmentKeys, err = helper.DeleteTaskAttachments(tx, taskIDs); err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("id IN ?", taskIDs).Delete(&models.Task{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+
+		// Riwayat task tetap disimpan, ditutup dengan event purged
+		activities := make([]models.TaskActivity, 0, len(taskIDs))
+		for _, taskID := range taskIDs {
+			activities = append(activities, helper.TaskEvent(taskID, userID, helper.ActivityPurged))
+		}
+		return helper.RecordTaskActivities(tx, activities)
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to purge tasks",
+			Error:   500,
+		})
+	}
+
+	helper.DeleteAttachmentObjects(attachmentKeys)
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Tasks permanently deleted",
+		Error:   200,
+		Data:    fiber.Map{"purged": purged},
+	})
+}
+
+// API Untuk List Category di Trash
+func GetTrashedCategories(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var cats []models.Category
+	if err := config.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Order("deleted_at DESC").Find(&cats).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to get trashed categories",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Trashed categories retrieved successfully",
+		Error:   200,
+		Data:    cats,
+	})
+}
+
+// API Untuk Restore Category dari Trash (One or Many)
+func RestoreCategories(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var req models.TrashIDs
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid data",
+			Error:   400,
+		})
+	}
+
+	if len(req.IDs) == 0 {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "No IDs provided",
+			Error:   400,
+		})
+	}
+
+	result := config.DB.Unscoped().Model(&models.Category{}).
+		Where("id IN ? AND user_id = ? AND deleted_at IS NOT NULL", req.IDs, userID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to restore categories",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Categories restored successfully",
+		Error:   200,
+		Data:    fiber.Map{"restored": result.RowsAffected},
+	})
+}
+
+// API Untuk Hapus Permanen Category dari Trash (One or Many)
+func PurgeCategories(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var req models.TrashIDs
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid data",
+			Error:   400,
+		})
+	}
+
+	if len(req.IDs) == 0 {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "No IDs provided",
+			Error:   400,
+		})
+	}
+
+	result := config.DB.Unscoped().
+		Where("id IN ? AND user_id = ? AND deleted_at IS NOT NULL", req.IDs, userID).
+		Delete(&models.Category{})
+	if result.Error != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to purge categories",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Categories permanently deleted",
+		Error:   200,
+		Data:    fiber.Map{"purged": result.RowsAffected},
+	})
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+)
+
+func TestDeleteAndRestoreTaskRestoresSubtasks(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t)
+
+	task := models.Task{
+		Title:  "Task with subtasks",
+		Status: "todo",
+		UserID: user.ID,
+		Subtasks: []models.Subtask{
+			{Title: "first", Position: 0},
+			{Title: "second", Position: 1},
+			{Title: "removed earlier", Position: 2},
+		},
+	}
+	if err := config.DB.Create(&task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	// Subtask yang dihapus sendiri sebelum task-nya tidak boleh ikut di-restore
+	removed := task.Subtasks[2]
+	if err := config.DB.Delete(&removed).Error; err != nil {
+		t.Fatalf("delete subtask: %v", err)
+	}
+
+	app := newTestApp(user.ID)
+	app.Delete("/tasks", DeleteTask)
+	app.Post("/trash/tasks/restore", RestoreTasks)
+
+	body := fmt.Sprintf(`{"ids":[%d]}`, task.ID)
+	doJSON(t, app, "DELETE", "/tasks", body)
+
+	var active int64
+	config.DB.Model(&models.Subtask{}).Where("task_id = ?", task.ID).Count(&active)
+	if active != 0 {
+		t.Fatalf("after delete: %d active subtasks, want 0", active)
+	}
+
+	doJSON(t, app, "POST", "/trash/tasks/restore", body)
+
+	if err := config.DB.First(&models.Task{}, task.ID).Error; err != nil {
+		t.Fatalf("task not restored: %v", err)
+	}
+
+	var restored []models.Subtask
+	config.DB.Where("task_id = ?", task.ID).Order("position ASC").Find(&restored)
+	if len(restored) != 2 || restored[0].Title != "first" || restored[1].Title != "second" {
+		t.Fatalf("restored subtasks = %+v, want [first second]", restored)
+	}
+
+	var stillDeleted int64
+	config.DB.Unscoped().Model(&models.Subtask{}).Where("id = ? AND deleted_at IS NOT NULL", removed.ID).Count(&stillDeleted)
+	if stillDeleted != 1 {
+		t.Errorf("subtask deleted before its task was restored too")
+	}
+}
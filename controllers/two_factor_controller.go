@@ -0,0 +1,439 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount            = 10
+	maxTwoFactorChallengeAttempt = 5
+)
+
+// Ganti semua recovery code user dengan yang baru
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := helper.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: helper.HashToken(code)}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verifikasi kode TOTP dan catat step-nya supaya tidak bisa dipakai ulang
+func verifyUserTOTP(user *models.User, code string) bool {
+	step, ok := helper.VerifyTOTP(user.TOTPSecret, strings.TrimSpace(code), user.TOTPLastStep)
+	if !ok {
+		return false
+	}
+
+	result := config.DB.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	user.TOTPLastStep = step
+	return true
+}
+
+// Pakai satu recovery code (sekali pakai)
+func useRecoveryCode(userID uint, code string) bool {
+	code = strings.ToLower(strings.TrimSpace(code))
+	result := config.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, helper.HashToken(code)).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+// API Untuk Setup 2FA (Generate Secret & QR URI)
+func SetupTwoFactor(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Two-factor authentication is already enabled",
+			Error:   400,
+		})
+	}
+
+	secret, err := helper.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate 2FA secret",
+			Error:   500,
+		})
+	}
+
+	if err := config.DB.Model(&user).Updates(map[string]any{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to save 2FA secret",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Scan the QR code and confirm with a code from your authenticator app",
+		Error:   200,
+		Data: fiber.Map{
+			"secret":      secret,
+			"otpauth_uri": helper.TOTPURI(secret, user.Email),
+		},
+	})
+}
+
+// API Untuk Konfirmasi 2FA (Aktifkan & Generate Recovery Codes)
+func ConfirmTwoFactor(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var input models.TwoFactorCode
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if user.TOTPEnabled || user.TOTPSecret == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Two-factor setup has not been started or is already enabled",
+			Error:   400,
+		})
+	}
+
+	if !verifyUserTOTP(&user, input.Code) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid 2FA code",
+			Error:   400,
+		})
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to enable 2FA",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Two-factor authentication enabled. Store your recovery codes safely",
+		Error:   200,
+		Data:    fiber.Map{"recovery_codes": codes},
+	})
+}
+
+// API Untuk Generate Ulang Recovery Codes
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var input models.TwoFactorCode
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if !user.TOTPEnabled {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Two-factor authentication is not enabled",
+			Error:   400,
+		})
+	}
+
+	if !verifyUserTOTP(&user, input.Code) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid 2FA code",
+			Error:   400,
+		})
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate recovery codes",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Recovery codes regenerated successfully",
+		Error:   200,
+		Data:    fiber.Map{"recovery_codes": codes},
+	})
+}
+
+// API Untuk Nonaktifkan 2FA
+func DisableTwoFactor(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var input models.DisableTwoFactor
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if !user.TOTPEnabled {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Two-factor authentication is not enabled",
+			Error:   400,
+		})
+	}
+
+	// Akun OAuth tanpa password cukup dengan kode 2FA / recovery code
+	if user.Password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Incorrect password",
+				Error:   400,
+			})
+		}
+	}
+
+	if !verifyUserTOTP(&user, input.Code) && !useRecoveryCode(user.ID, input.Code) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid 2FA code",
+			Error:   400,
+		})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]any{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to disable 2FA",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+		Error:   200,
+	})
+}
+
+// API Untuk Login Tahap 2 (Verifikasi Kode 2FA)
+func VerifyTwoFactorLogin(c *fiber.Ctx) error {
+	var input models.TwoFactorLogin
+	if err := c.BodyParser(&input); err != nil || input.ChallengeToken == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	var challenge models.TwoFactorChallenge
+	if err := config.DB.Where("token_hash = ? AND expires_at > ?", helper.HashToken(input.ChallengeToken), time.Now()).
+		First(&challenge).Error; err != nil {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid or Expired 2FA Challenge",
+			Error:   401,
+		})
+	}
+
+	// Percobaan dipesan dulu secara atomik supaya request paralel tidak bisa melewati batas
+	reserved := config.DB.Model(&models.TwoFactorChallenge{}).
+		Where("id = ? AND attempts < ?", challenge.ID, maxTwoFactorChallengeAttempt).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if reserved.Error != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to verify 2FA code",
+			Error:   500,
+		})
+	}
+	if reserved.RowsAffected == 0 {
+		config.DB.Unscoped().Delete(&challenge)
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Too many attempts, please login again",
+			Error:   401,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, challenge.UserID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	verified := false
+	if input.Code != "" {
+		verified = verifyUserTOTP(&user, input.Code)
+	} else if input.RecoveryCode != "" {
+		verified = useRecoveryCode(user.ID, input.RecoveryCode)
+	}
+
+	if !verified {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid 2FA code",
+			Error:   401,
+		})
+	}
+
+	// Challenge sekali pakai: hanya request yang berhasil menghapusnya yang dapat token
+	if config.DB.Unscoped().Delete(&challenge).RowsAffected == 0 {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid or Expired 2FA Challenge",
+			Error:   401,
+		})
+	}
+
+	if err := helper.CheckAccountStatus(user); err != nil {
+		return accountStatusError(c, err)
+	}
+
+	tokens, err := helper.IssueTokenPair(user.ID, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate token",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Login successfully",
+		Error:   200,
+		Data:    loginData(user, tokens),
+	})
+}
@@ -1,16 +1,18 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/MashuNakamura/todolist-backend/config"
 	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/middleware"
 	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/MashuNakamura/todolist-backend/ratelimit"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 func Register(c *fiber.Ctx) error {
@@ -67,6 +69,7 @@ func Register(c *fiber.Ctx) error {
 		Password: string(hash),
 	}
 
+	// Akun baru mulai dalam keadaan belum verified
 	if err := config.DB.Create(&newUser).Error; err != nil {
 		return c.Status(500).JSON(models.Ret{
 			Success: false,
@@ -75,11 +78,16 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := sendVerificationEmail(newUser); err != nil {
+		// User tetap dibuat, verifikasi bisa dikirim ulang lewat /api/resend-verification
+		fmt.Println("Error sending verification email:", err)
+	}
+
 	newUser.Password = ""
 
 	return c.JSON(models.Ret{
 		Success: true,
-		Message: "User created successfully",
+		Message: "User created successfully. Please check your email to verify your account",
 		Error:   200,
 		Data:    newUser,
 	})
@@ -109,7 +117,12 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
+	if lockout := ratelimit.LoginLockout(input.Email); lockout > 0 {
+		return middleware.TooManyRequests(c, lockout, "Too many failed login attempts, please try again later")
+	}
+
 	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		ratelimit.RecordLoginFailure(input.Email)
 		return c.Status(401).JSON(models.Ret{
 			Success: false,
 			Message: "Invalid email or password",
@@ -118,6 +131,7 @@ func Login(c *fiber.Ctx) error {
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		ratelimit.RecordLoginFailure(input.Email)
 		return c.Status(401).JSON(models.Ret{
 			Success: false,
 			Message: "Invalid password",
@@ -125,12 +139,44 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	ratelimit.ResetLoginFailures(input.Email)
+
+	if err := helper.CheckAccountStatus(user); err != nil {
+		return accountStatusError(c, err)
+	}
+
+	if !user.EmailVerified && helper.EmailVerificationMode() == helper.EmailVerificationLogin {
+		return c.Status(403).JSON(models.Ret{
+			Success: false,
+			Message: "Please verify your email before logging in",
+			Error:   403,
+		})
+	}
+
+	// 2FA aktif: token baru diberikan setelah kode TOTP diverifikasi
+	if user.TOTPEnabled {
+		challenge, expiresAt, err := helper.CreateTwoFactorChallenge(user.ID)
+		if err != nil {
+			return c.Status(500).JSON(models.Ret{
+				Success: false,
+				Message: "Failed to create 2FA challenge",
+				Error:   500,
+			})
+		}
+
+		return c.JSON(models.Ret{
+			Success: true,
+			Message: "Two-factor authentication required",
+			Error:   200,
+			Data: fiber.Map{
+				"two_factor_required": true,
+				"challenge_token":     challenge,
+				"expires":             expiresAt.Unix(),
+			},
+		})
+	}
 
-	token, err := claims.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tokens, err := helper.IssueTokenPair(user.ID, c.Get(fiber.HeaderUserAgent), c.IP())
 	if err != nil {
 		return c.Status(500).JSON(models.Ret{
 			Success: false,
@@ -139,20 +185,41 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	user.Password = ""
-
 	return c.JSON(models.Ret{
 		Success: true,
 		Message: "Login successfully",
 		Error:   200,
-		Data: fiber.Map{
-			"user":    user,
-			"token":   token,
-			"expires": time.Now().Add(time.Hour * 24).Unix(),
-		},
+		Data:    loginData(user, tokens),
+	})
+}
+
+// Response untuk akun yang di-disable / wajib reset password oleh admin
+func accountStatusError(c *fiber.Ctx, err error) error {
+	message := "Your account has been disabled"
+	if errors.Is(err, helper.ErrPasswordResetRequired) {
+		message = "Password reset required, please reset your password via forgot password"
+	}
+
+	return c.Status(403).JSON(models.Ret{
+		Success: false,
+		Message: message,
+		Error:   403,
 	})
 }
 
+// Response login (dipakai juga oleh login 2FA)
+func loginData(user models.User, tokens models.TokenPair) fiber.Map {
+	user.Password = ""
+
+	return fiber.Map{
+		"user":            user,
+		"token":           tokens.AccessToken,
+		"expires":         tokens.ExpiresAt,
+		"refresh_token":   tokens.RefreshToken,
+		"refresh_expires": tokens.RefreshExpiresAt,
+	}
+}
+
 // API Untuk Update Profile
 func UpdateProfile(c *fiber.Ctx) error {
 	val := c.Locals("user_id")
@@ -208,6 +275,12 @@ func UpdateProfile(c *fiber.Ctx) error {
 	})
 }
 
+// Pembatasan OTP reset password
+const (
+	maxOTPAttempts     = 5
+	otpCooldownSeconds = 60
+)
+
 // API Untuk Forgot Password
 func ForgotPassword(c *fiber.Ctx) error {
 	var input models.ForgotPassword
@@ -244,9 +317,27 @@ func ForgotPassword(c *fiber.Ctx) error {
 		})
 	}
 
+	if time.Now().Unix()-user_cp.OTPSentAt < otpCooldownSeconds {
+		return c.Status(429).JSON(models.Ret{
+			Success: false,
+			Message: "Please wait before requesting another OTP",
+			Error:   429,
+		})
+	}
+
 	otp := helper.GenerateOTP()
-	user_cp.OTP = otp
+	otpHash, err := helper.HashOTP(otp)
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate OTP",
+			Error:   500,
+		})
+	}
+	user_cp.OTP = otpHash
 	user_cp.OTPExpiry = time.Now().Add(5 * time.Minute).Unix()
+	user_cp.OTPAttempts = 0
+	user_cp.OTPSentAt = time.Now().Unix()
 
 	if err := config.DB.Save(&user_cp).Error; err != nil {
 		return c.Status(500).JSON(models.Ret{
@@ -359,7 +450,7 @@ func ResetPassword(c *fiber.Ctx) error {
 	}
 
 	var user models.User
-	if err := config.DB.Where("email = ? AND otp = ?", input.Email, input.OTP).First(&user).Error; err != nil {
+	if err := config.DB.Where("email = ? AND otp <> ''", input.Email).First(&user).Error; err != nil {
 		return c.Status(400).JSON(models.Ret{
 			Success: false,
 			Message: "Invalid Email or OTP",
@@ -375,6 +466,28 @@ func ResetPassword(c *fiber.Ctx) error {
 		})
 	}
 
+	if !helper.CheckOTP(user.OTP, input.OTP) {
+		// Hitung percobaan gagal, OTP hangus setelah maxOTPAttempts
+		result := config.DB.Model(&models.User{}).
+			Where("id = ? AND otp_attempts < ?", user.ID, maxOTPAttempts-1).
+			UpdateColumn("otp_attempts", gorm.Expr("otp_attempts + 1"))
+		if result.Error == nil && result.RowsAffected == 0 {
+			config.DB.Model(&models.User{}).Where("id = ?", user.ID).
+				UpdateColumns(map[string]any{"otp": "", "otp_expiry": 0, "otp_attempts": 0})
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Too many failed attempts, please request a new OTP",
+				Error:   400,
+			})
+		}
+
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid Email or OTP",
+			Error:   400,
+		})
+	}
+
 	if !helper.IsStrongPassword(input.Password) {
 		return c.Status(400).JSON(models.Ret{
 			Success: false,
@@ -400,8 +513,17 @@ func ResetPassword(c *fiber.Ctx) error {
 	user.Password = string(hashNewPassword)
 	user.OTP = ""
 	user.OTPExpiry = 0
-
-	if err := config.DB.Save(&user).Error; err != nil {
+	user.OTPAttempts = 0
+	user.PasswordResetRequired = false
+
+	// Semua session & token lama ikut dicabut, reset password adalah respon utama kalau akun dibobol
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return helper.RevokeUserCredentials(tx, user.ID)
+	})
+	if err != nil {
 		return c.Status(500).JSON(models.Ret{
 			Success: false,
 			Message: "Failed to reset password",
@@ -446,6 +568,14 @@ func ChangePassword(c *fiber.Ctx) error {
 		})
 	}
 
+	if user.Password == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Your account has no password yet, please set one first",
+			Error:   400,
+		})
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.OldPassword)); err != nil {
 		return c.Status(400).JSON(models.Ret{
 			Success: false,
@@ -481,7 +611,16 @@ func ChangePassword(c *fiber.Ctx) error {
 	}
 
 	user.Password = string(hashNewPassword)
-	if err := config.DB.Save(&user).Error; err != nil {
+
+	// Session lain & API token dicabut, hanya device yang sedang dipakai tetap login
+	currentID, _ := c.Locals("session_id").(string)
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return helper.RevokeOtherCredentials(tx, user.ID, currentID)
+	})
+	if err != nil {
 		return c.Status(500).JSON(models.Ret{
 			Success: false,
 			Message: "Failed to change password",
@@ -496,6 +635,78 @@ func ChangePassword(c *fiber.Ctx) error {
 	})
 }
 
+// API Untuk Set Password Pertama (akun yang dibuat lewat social login)
+func SetPassword(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	var input models.SetPassword
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(models.Ret{
+			Success: false,
+			Message: "User not found",
+			Error:   404,
+		})
+	}
+
+	if user.Password != "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Password already set, use change password instead",
+			Error:   400,
+		})
+	}
+
+	if !helper.IsStrongPassword(input.NewPassword) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Password must be at least 8 characters long and contain at least one uppercase letter, one lowercase letter, one number, and one special character",
+			Error:   400,
+		})
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), 10)
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to generate password hash",
+			Error:   500,
+		})
+	}
+
+	// Kondisi password = '' mencegah race dengan request set-password lain
+	result := config.DB.Model(&models.User{}).Where("id = ? AND password = ''", userID).Update("password", string(hash))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to set password",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Password set successfully",
+		Error:   200,
+	})
+}
+
 // API Untuk Get Profile
 func GetProfile(c *fiber.Ctx) error {
 	val := c.Locals("user_id")
@@ -509,7 +720,7 @@ func GetProfile(c *fiber.Ctx) error {
 	}
 
 	var user models.User
-	if err := config.DB.Select("id, name, email").First(&user, userID).Error; err != nil {
+	if err := config.DB.Select("id, name, email, avatar_key").First(&user, userID).Error; err != nil {
 		return c.Status(404).JSON(models.Ret{
 			Success: false,
 			Message: "User not found",
@@ -517,6 +728,9 @@ func GetProfile(c *fiber.Ctx) error {
 		})
 	}
 
+	pref := helper.GetPreferences(userID)
+	user.Preference = &pref
+
 	return c.JSON(models.Ret{
 		Success: true,
 		Message: "User found",
@@ -525,8 +739,74 @@ func GetProfile(c *fiber.Ctx) error {
 	})
 }
 
-// API Untuk Logout
+// API Untuk Refresh Token (Rotation)
+func RefreshToken(c *fiber.Ctx) error {
+	var input models.RefreshTokenRequest
+	if err := c.BodyParser(&input); err != nil || input.RefreshToken == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Refresh token is required",
+			Error:   400,
+		})
+	}
+
+	tokens, err := helper.RotateRefreshToken(input.RefreshToken, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		if errors.Is(err, helper.ErrInvalidRefreshToken) || errors.Is(err, helper.ErrRefreshTokenReused) {
+			return c.Status(401).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid or Expired Refresh Token",
+				Error:   401,
+			})
+		}
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to refresh token",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Token refreshed successfully",
+		Error:   200,
+		Data:    tokens,
+	})
+}
+
+// API Untuk Logout (Revoke Refresh Token Family)
 func Logout(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+
+	// Body opsional: kalau refresh token tidak dikirim, pakai session dari access token
+	familyID, _ := c.Locals("session_id").(string)
+
+	var input models.RefreshTokenRequest
+	if err := c.BodyParser(&input); err == nil && input.RefreshToken != "" {
+		var refresh models.RefreshToken
+		if err := config.DB.Where("token_hash = ? AND user_id = ?", helper.HashToken(input.RefreshToken), userID).First(&refresh).Error; err == nil {
+			familyID = refresh.FamilyID
+		}
+	}
+
+	if familyID != "" {
+		if err := helper.RevokeSession(familyID); err != nil {
+			return c.Status(500).JSON(models.Ret{
+				Success: false,
+				Message: "Failed to logout",
+				Error:   500,
+			})
+		}
+	}
+
 	return c.JSON(models.Ret{
 		Success: true,
 		Message: "Logout successfully",
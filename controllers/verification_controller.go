@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	emailVerificationTTL         = 24 * time.Hour
+	emailVerificationCooldown    = time.Minute
+	maxEmailVerificationAttempts = 5
+)
+
+// Buat (atau ganti) token & kode verifikasi lalu kirim ke email user
+func sendVerificationEmail(user models.User) error {
+	token, err := helper.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	code := helper.GenerateOTP()
+	codeHash, err := helper.HashOTP(code)
+	if err != nil {
+		return err
+	}
+
+	verification := models.EmailVerification{
+		UserID:    user.ID,
+		TokenHash: helper.HashToken(token),
+		CodeHash:  codeHash,
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+		SentAt:    time.Now(),
+	}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token_hash", "code_hash", "expires_at", "attempts", "sent_at", "updated_at"}),
+	}).Create(&verification).Error; err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", os.Getenv("FRONTEND_URL"), url.QueryEscape(token))
+
+	emailBody := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<body style="font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px;">
+		<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; padding: 20px; border-radius: 8px; text-align: center;">
+			<h2 style="color: #2c3e50;">Verify Your Email</h2>
+			<p>Hello %s,</p>
+			<p>Thanks for signing up! Click the button below to verify your email address:</p>
+			<p><a href="%s" style="background-color: #2c3e50; color: #ffffff; padding: 10px 20px; border-radius: 5px; text-decoration: none;">Verify Email</a></p>
+			<p>Or enter this code in the app:</p>
+			<div style="font-size: 32px; font-weight: bold; color: #2c3e50; letter-spacing: 5px;">%s</div>
+			<p>This link and code are valid for 24 hours.</p>
+			<p style="font-size: 12px; color: #7f8c8d;">&copy; %d Koto Todolist. All rights reserved.</p>
+		</div>
+	</body>
+	</html>
+	`, html.EscapeString(user.Name), link, code, time.Now().Year())
+
+	return helper.SendEmail(user.Email, "Verify Your Email", emailBody)
+}
+
+// API Untuk Verify Email
+func VerifyEmail(c *fiber.Ctx) error {
+	var input models.VerifyEmail
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Invalid input",
+			Error:   400,
+		})
+	}
+
+	var verification models.EmailVerification
+	switch {
+	case input.Token != "":
+		if err := config.DB.Where("token_hash = ?", helper.HashToken(input.Token)).First(&verification).Error; err != nil {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid or expired verification link",
+				Error:   400,
+			})
+		}
+	case input.Email != "" && input.Code != "":
+		var user models.User
+		if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil ||
+			config.DB.Where("user_id = ?", user.ID).First(&verification).Error != nil {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid Email or Code",
+				Error:   400,
+			})
+		}
+
+		if verification.Attempts >= maxEmailVerificationAttempts {
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Too many attempts, please request a new code",
+				Error:   400,
+			})
+		}
+
+		if !helper.CheckOTP(verification.CodeHash, input.Code) {
+			config.DB.Model(&verification).UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+			return c.Status(400).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid Email or Code",
+				Error:   400,
+			})
+		}
+	default:
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Token or Email and Code are required",
+			Error:   400,
+		})
+	}
+
+	if time.Now().After(verification.ExpiresAt) {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Verification has expired, please request a new one",
+			Error:   400,
+		})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", verification.UserID).Update("email_verified", true).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&verification).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to verify email",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(models.Ret{
+		Success: true,
+		Message: "Email verified successfully",
+		Error:   200,
+	})
+}
+
+// API Untuk Resend Email Verification
+func ResendVerification(c *fiber.Ctx) error {
+	var input models.ForgotPassword
+	if err := c.BodyParser(&input); err != nil || input.Email == "" {
+		return c.Status(400).JSON(models.Ret{
+			Success: false,
+			Message: "Email is required",
+			Error:   400,
+		})
+	}
+
+	// Response sama untuk email yang tidak terdaftar / sudah verified
+	response := models.Ret{
+		Success: true,
+		Message: "If the account exists and is not verified, a verification email has been sent",
+		Error:   200,
+	}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil || user.EmailVerified {
+		return c.JSON(response)
+	}
+
+	var verification models.EmailVerification
+	if err := config.DB.Where("user_id = ?", user.ID).First(&verification).Error; err == nil &&
+		time.Since(verification.SentAt) < emailVerificationCooldown {
+		return c.Status(429).JSON(models.Ret{
+			Success: false,
+			Message: "Please wait before requesting another verification email",
+			Error:   429,
+		})
+	}
+
+	if err := sendVerificationEmail(user); err != nil {
+		fmt.Println("Error sending verification email:", err)
+		return c.Status(500).JSON(models.Ret{
+			Success: false,
+			Message: "Failed to send verification email",
+			Error:   500,
+		})
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,75 @@
+package helper
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+var ValidRoles = map[string]bool{RoleUser: true, RoleAdmin: true}
+
+var (
+	ErrAccountDisabled       = errors.New("account has been disabled")
+	ErrPasswordResetRequired = errors.New("password reset required")
+)
+
+// Cek apakah user boleh memulai session baru (tidak disabled / wajib reset password)
+func CheckAccountStatus(user models.User) error {
+	if user.DisabledAt != nil {
+		return ErrAccountDisabled
+	}
+	if user.PasswordResetRequired {
+		return ErrPasswordResetRequired
+	}
+	return nil
+}
+
+// Cabut semua session, refresh token & API token milik user
+func RevokeUserCredentials(tx *gorm.DB, userID uint) error {
+	return RevokeOtherCredentials(tx, userID, "")
+}
+
+// Sama seperti RevokeUserCredentials, tapi session keepFamilyID (device yang sedang dipakai) tetap aktif
+func RevokeOtherCredentials(tx *gorm.DB, userID uint, keepFamilyID string) error {
+	now := time.Now()
+	if err := tx.Model(&models.Session{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, keepFamilyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, keepFamilyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// Jadikan admin semua akun yang email-nya ada di ADMIN_EMAILS
+func PromoteAdmins() error {
+	var emails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	if len(emails) == 0 {
+		return nil
+	}
+
+	return config.DB.Model(&models.User{}).
+		Where("email IN ? AND role <> ?", emails, RoleAdmin).
+		Update("role", RoleAdmin).Error
+}
@@ -0,0 +1,154 @@
+package helper
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	ActivityCreated  = "created"
+	ActivityUpdated  = "updated"
+	ActivityDeleted  = "deleted"
+	ActivityRestored = "restored"
+	ActivityPurged   = "purged"
+
+	ActivitySubtaskCreated = "subtask_created"
+	ActivitySubtaskUpdated = "subtask_updated"
+	ActivitySubtaskDeleted = "subtask_deleted"
+)
+
+// Nilai kosong dicatat sebagai null
+func activityValue(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// Array dicatat sebagai JSON supaya null (default) & [] (kosong) tetap beda
+func activityJSON(value any, isNil bool) *string {
+	if isNil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return activityValue(string(data))
+}
+
+// Field task yang dicatat di activity history
+var taskActivityFields = []struct {
+	name  string
+	value func(task models.Task) *string
+}{
+	{"title", func(t models.Task) *string { return activityValue(t.Title) }},
+	{"short_desc", func(t models.Task) *string { return activityValue(t.ShortDesc) }},
+	{"long_desc", func(t models.Task) *string { return activityValue(t.LongDesc) }},
+	{"priority", func(t models.Task) *string { return activityValue(t.Priority) }},
+	{"status", func(t models.Task) *string { return activityValue(t.Status) }},
+	{"time", func(t models.Task) *string { return activityValue(t.Time) }},
+	{"due_date", func(t models.Task) *string {
+		if t.DueDate == nil {
+			return nil
+		}
+		return activityValue(t.DueDate.UTC().Format(time.RFC3339))
+	}},
+	{"tags", func(t models.Task) *string { return activityJSON(t.Tags, t.Tags == nil) }},
+	{"recurrence", func(t models.Task) *string { return activityValue(t.Recurrence) }},
+	{"reminder_offsets", func(t models.Task) *string {
+		return activityJSON(t.ReminderOffsets, t.ReminderOffsets == nil)
+	}},
+}
+
+func sameActivityValue(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Satu activity "updated" per field task yang berubah
+func TaskChanges(before, after models.Task, userID uint) []models.TaskActivity {
+	var activities []models.TaskActivity
+	for _, field := range taskActivityFields {
+		oldValue, newValue := field.value(before), field.value(after)
+		if sameActivityValue(oldValue, newValue) {
+			continue
+		}
+		activities = append(activities, models.TaskActivity{
+			TaskID:   after.ID,
+			UserID:   userID,
+			Action:   ActivityUpdated,
+			Field:    field.name,
+			OldValue: oldValue,
+			NewValue: newValue,
+		})
+	}
+	return activities
+}
+
+// Field subtask yang dicatat di activity history
+var subtaskActivityFields = []struct {
+	name  string
+	value func(subtask models.Subtask) *string
+}{
+	{"title", func(s models.Subtask) *string { return activityValue(s.Title) }},
+	{"done", func(s models.Subtask) *string { return activityValue(strconv.FormatBool(s.Done)) }},
+	{"position", func(s models.Subtask) *string { return activityValue(strconv.Itoa(s.Position)) }},
+}
+
+// Satu activity "subtask_updated" per field subtask yang berubah
+func SubtaskChanges(before, after models.Subtask, userID uint) []models.TaskActivity {
+	var activities []models.TaskActivity
+	for _, field := range subtaskActivityFields {
+		oldValue, newValue := field.value(before), field.value(after)
+		if sameActivityValue(oldValue, newValue) {
+			continue
+		}
+		activities = append(activities, models.TaskActivity{
+			TaskID:    after.TaskID,
+			SubtaskID: &after.ID,
+			UserID:    userID,
+			Action:    ActivitySubtaskUpdated,
+			Field:     field.name,
+			OldValue:  oldValue,
+			NewValue:  newValue,
+		})
+	}
+	return activities
+}
+
+// Activity subtask dibuat / dihapus, judul disimpan supaya timeline tetap terbaca setelah dihapus
+func SubtaskEvent(subtask models.Subtask, userID uint, action string) models.TaskActivity {
+	activity := models.TaskActivity{
+		TaskID:    subtask.TaskID,
+		SubtaskID: &subtask.ID,
+		UserID:    userID,
+		Action:    action,
+		Field:     "title",
+	}
+	if action == ActivitySubtaskDeleted {
+		activity.OldValue = activityValue(subtask.Title)
+	} else {
+		activity.NewValue = activityValue(subtask.Title)
+	}
+	return activity
+}
+
+// Activity untuk aksi pada task secara utuh (created, deleted, restored, purged)
+func TaskEvent(taskID, userID uint, action string) models.TaskActivity {
+	return models.TaskActivity{TaskID: taskID, UserID: userID, Action: action}
+}
+
+// Simpan activity di transaksi pemanggil, jadi riwayat hanya tercatat kalau perubahannya commit
+func RecordTaskActivities(tx *gorm.DB, activities []models.TaskActivity) error {
+	if len(activities) == 0 {
+		return nil
+	}
+	return tx.Create(&activities).Error
+}
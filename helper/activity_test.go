@@ -0,0 +1,56 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/MashuNakamura/todolist-backend/models"
+)
+
+func TestSubtaskChanges(t *testing.T) {
+	before := models.Subtask{TaskID: 7, Title: "Beli susu", Done: false, Position: 1}
+	before.ID = 3
+	after := before
+	after.Done = true
+	after.Position = 2
+
+	activities := SubtaskChanges(before, after, 9)
+	if len(activities) != 2 {
+		t.Fatalf("got %d activities, want 2", len(activities))
+	}
+
+	want := []struct{ field, old, new string }{
+		{"done", "false", "true"},
+		{"position", "1", "2"},
+	}
+	for i, w := range want {
+		a := activities[i]
+		if a.TaskID != 7 || a.SubtaskID == nil || *a.SubtaskID != 3 || a.UserID != 9 || a.Action != ActivitySubtaskUpdated {
+			t.Errorf("activity %d: unexpected ids/action %+v", i, a)
+		}
+		if a.Field != w.field || *a.OldValue != w.old || *a.NewValue != w.new {
+			t.Errorf("activity %d: got %s %s -> %s, want %s %s -> %s", i, a.Field, *a.OldValue, *a.NewValue, w.field, w.old, w.new)
+		}
+	}
+
+	if got := SubtaskChanges(before, before, 9); len(got) != 0 {
+		t.Errorf("unchanged subtask produced %d activities", len(got))
+	}
+}
+
+func TestSubtaskEvent(t *testing.T) {
+	subtask := models.Subtask{TaskID: 7, Title: "Beli susu"}
+	subtask.ID = 3
+
+	created := SubtaskEvent(subtask, 9, ActivitySubtaskCreated)
+	if created.OldValue != nil || created.NewValue == nil || *created.NewValue != "Beli susu" {
+		t.Errorf("created: got old=%v new=%v", created.OldValue, created.NewValue)
+	}
+
+	deleted := SubtaskEvent(subtask, 9, ActivitySubtaskDeleted)
+	if deleted.NewValue != nil || deleted.OldValue == nil || *deleted.OldValue != "Beli susu" {
+		t.Errorf("deleted: got old=%v new=%v", deleted.OldValue, deleted.NewValue)
+	}
+	if deleted.SubtaskID == nil || *deleted.SubtaskID != 3 || deleted.TaskID != 7 {
+		t.Errorf("deleted: unexpected ids %+v", deleted)
+	}
+}
@@ -0,0 +1,48 @@
+package helper
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+)
+
+// Prefix supaya personal access token mudah dikenali (dan di-scan kalau bocor)
+const APITokenPrefix = "tdl_pat_"
+
+// Scope yang bisa diberikan ke personal access token
+var APITokenScopes = []string{"tasks:read", "tasks:write", "categories:read", "categories:write"}
+
+var ErrInvalidAPIToken = errors.New("invalid or expired API token")
+
+// Last used cukup di-update paling sering sekali per menit
+const apiTokenTouchInterval = time.Minute
+
+// Buat token mentah baru beserta prefix untuk ditampilkan di list
+func GenerateAPIToken() (string, string, error) {
+	random, err := RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	token := APITokenPrefix + random
+	return token, token[:len(APITokenPrefix)+6], nil
+}
+
+// Cari token yang masih aktif, sekalian catat last used
+func AuthenticateAPIToken(raw string) (*models.APIToken, error) {
+	var apiToken models.APIToken
+	if err := config.DB.Where("token_hash = ? AND revoked_at IS NULL", HashToken(raw)).First(&apiToken).Error; err != nil {
+		return nil, ErrInvalidAPIToken
+	}
+
+	now := time.Now()
+	if apiToken.ExpiresAt != nil && now.After(*apiToken.ExpiresAt) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) > apiTokenTouchInterval {
+		config.DB.Model(&apiToken).UpdateColumn("last_used_at", now)
+	}
+	return &apiToken, nil
+}
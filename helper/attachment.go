@@ -0,0 +1,61 @@
+package helper
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/MashuNakamura/todolist-backend/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAttachmentQuotaMB = 100
+	defaultAttachmentMaxMB   = 25
+)
+
+func envMegabytes(name string, fallback int) int64 {
+	mb, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || mb <= 0 {
+		mb = fallback
+	}
+	return int64(mb) * 1024 * 1024
+}
+
+// Total ukuran attachment per user dari ATTACHMENT_QUOTA_MB (default 100 MB)
+func AttachmentQuota() int64 {
+	return envMegabytes("ATTACHMENT_QUOTA_MB", defaultAttachmentQuotaMB)
+}
+
+// Ukuran maksimal satu file dari ATTACHMENT_MAX_MB (default 25 MB)
+func MaxAttachmentSize() int64 {
+	return envMegabytes("ATTACHMENT_MAX_MB", defaultAttachmentMaxMB)
+}
+
+// Hapus row attachment milik task, key storage-nya dikembalikan untuk dihapus setelah commit
+func DeleteTaskAttachments(tx *gorm.DB, taskIDs []uint) ([]string, error) {
+	if len(taskIDs) == 0 {
+		return nil, nil
+	}
+
+	var keys []string
+	if err := tx.Model(&models.TaskAttachment{}).Where("task_id IN ?", taskIDs).Pluck("storage_key", &keys).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id IN ?", taskIDs).Delete(&models.TaskAttachment{}).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Hapus file attachment di storage, gagal cukup di-log karena metadata sudah terhapus
+func DeleteAttachmentObjects(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	if err := storage.DeleteAll(context.Background(), storage.Default(), keys...); err != nil {
+		log.Println("Failed to delete attachment files:", err)
+	}
+}
@@ -1,12 +1,17 @@
 package helper
 
 import (
+	"crypto/rand"
 	"fmt"
-	"math/rand"
+	"math/big"
 	"net/smtp"
 	"os"
 	"regexp"
+	"strings"
+	"time"
 	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func IsValidEmail(email string) bool {
@@ -47,13 +52,24 @@ func IsStrongPassword(password string) bool {
 	return hasMinLen && hasUpper && hasLower && hasNumber && hasSpecial
 }
 
+// OTP 6 digit dari CSPRNG
 func GenerateOTP() string {
-	digits := "0123456789"
-	otp := make([]byte, 6)
-	for i := range otp {
-		otp[i] = digits[rand.Intn(len(digits))]
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		panic("helper: crypto/rand unavailable: " + err.Error())
 	}
-	return string(otp)
+	return fmt.Sprintf("%06d", n.Int64())
+}
+
+// OTP disimpan sebagai hash bcrypt supaya row yang bocor tidak mudah dibalik
+func HashOTP(otp string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(otp), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Bandingkan OTP dari user dengan hash yang tersimpan
+func CheckOTP(hash, otp string) bool {
+	return hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(otp)) == nil
 }
 
 func SendEmail(to string, subject, body string) error {
@@ -79,3 +95,29 @@ func SendEmail(to string, subject, body string) error {
 
 	return smtp.SendMail(addr, auth, from, []string{to}, msg)
 }
+
+// Terima timestamp RFC3339 atau tanggal 2006-01-02 (dianggap tengah malam di time zone user)
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	parsedTime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.ParseInLocation("2006-01-02", value, loc)
+	}
+	return parsedTime, nil
+}
+
+// Mode pembatasan akun yang belum verifikasi email (EMAIL_VERIFICATION_MODE)
+const (
+	EmailVerificationOff      = "off"      // tidak ada pembatasan
+	EmailVerificationReadOnly = "readonly" // boleh login, tapi hanya bisa membaca data
+	EmailVerificationLogin    = "login"    // tidak boleh login sama sekali
+)
+
+// Mode dari EMAIL_VERIFICATION_MODE, default readonly
+func EmailVerificationMode() string {
+	switch mode := strings.ToLower(os.Getenv("EMAIL_VERIFICATION_MODE")); mode {
+	case EmailVerificationOff, EmailVerificationLogin:
+		return mode
+	default:
+		return EmailVerificationReadOnly
+	}
+}
@@ -0,0 +1,116 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decoder GIF (frame pertama)
+	"image/jpeg"
+	_ "image/png" // decoder PNG
+	"net/http"
+
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/MashuNakamura/todolist-backend/storage"
+)
+
+// Batas dimensi gambar yang mau di-decode (mencegah decompression bomb)
+const maxImagePixels = 40_000_000
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image type (jpeg, png, gif)")
+	ErrImageTooLarge    = errors.New("image dimensions are too large")
+)
+
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Decode gambar berdasarkan isi file (Content-Type dari client tidak dipercaya), dimensi dicek dulu
+func DecodeImage(data []byte) (image.Image, error) {
+	if !allowedImageTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	return img, nil
+}
+
+// Crop tengah jadi persegi lalu perkecil ke size x size (box filter: rata-rata
+// pixel sumber per pixel target). Area transparan diganti putih
+func SquareThumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side)
+
+	offset := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+	src := image.NewRGBA(crop)
+	draw.Draw(src, crop, &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(src, crop, img, offset, draw.Over)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for dy := 0; dy < size; dy++ {
+		sy0 := dy * side / size
+		sy1 := max((dy+1)*side/size, sy0+1)
+
+		for dx := 0; dx < size; dx++ {
+			sx0 := dx * side / size
+			sx1 := max((dx+1)*side/size, sx0+1)
+
+			var r, g, b, n int
+			for sy := sy0; sy < sy1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := sx0; sx < sx1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					n++
+				}
+			}
+
+			i := dy*dst.Stride + dx*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
+
+// Encode gambar ke JPEG dengan quality tertentu
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Hapus semua thumbnail di bawah prefix avatar
+func DeleteAvatarObjects(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+
+	keys := make([]string, 0, len(models.AvatarSizes))
+	for _, size := range models.AvatarSizes {
+		keys = append(keys, models.AvatarObjectKey(prefix, size))
+	}
+	return storage.DeleteAll(context.Background(), storage.Default(), keys...)
+}
@@ -0,0 +1,333 @@
+package helper
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Konfigurasi token service (semua opsional):
+//
+//	JWT_PRIVATE_KEY_FILE  PEM private key RSA / Ed25519 untuk signing
+//	JWT_SECRET            fallback kalau JWT_PRIVATE_KEY_FILE kosong: key Ed25519 diturunkan dari secret ini
+//	ALLOW_EPHEMERAL_KEYS  "true" hanya untuk development: tanpa key & secret dibuat key random per proses
+//	JWT_KEY_ID            kid untuk key signing (default: RFC 7638 thumbprint)
+//	JWT_PUBLIC_KEY_FILES  public key lain yang masih diterima, "path" atau "kid=path", dipisah koma
+//	JWT_ISSUER            claim iss (default todolist-backend)
+//	JWT_AUDIENCE          claim aud (default todolist-api)
+const (
+	defaultJWTIssuer   = "todolist-backend"
+	defaultJWTAudience = "todolist-api"
+)
+
+var (
+	ErrUnknownSigningKey = errors.New("unknown signing key")
+	ErrNoSigningKey      = errors.New("JWT_PRIVATE_KEY_FILE or JWT_SECRET must be set (ALLOW_EPHEMERAL_KEYS=true for local development only)")
+)
+
+// Label supaya key JWT dari JWT_SECRET tidak sama dengan turunan lain dari secret yang sama
+const jwtSecretKeyLabel = "todolist-backend/jwt-ed25519/v1:"
+
+// Boleh pakai key random per proses (ALLOW_EPHEMERAL_KEYS), hanya untuk development:
+// token tidak valid setelah restart dan tidak diterima replica lain
+func EphemeralKeysAllowed() bool {
+	return strings.EqualFold(os.Getenv("ALLOW_EPHEMERAL_KEYS"), "true")
+}
+
+// Claims di dalam access token
+type AccessClaims struct {
+	UserID    uint   `json:"user_id"`
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+type keySet struct {
+	signer     crypto.Signer
+	signingKey jwtKey
+	verify     map[string]jwtKey
+	order      []string
+}
+
+var (
+	keysOnce sync.Once
+	keys     *keySet
+	keysErr  error
+)
+
+// Load key JWT sekali saja, panggil saat startup supaya error langsung ketahuan
+func LoadSigningKeys() error {
+	keysOnce.Do(func() {
+		keys, keysErr = loadKeySet()
+	})
+	return keysErr
+}
+
+func loadKeySet() (*keySet, error) {
+	set := &keySet{verify: map[string]jwtKey{}}
+
+	var signer crypto.Signer
+	if path := os.Getenv("JWT_PRIVATE_KEY_FILE"); path != "" {
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		signer, err = parsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+	} else if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		// Deployment lama yang hanya punya JWT_SECRET: key tetap sama setelah restart & di semua replica
+		log.Println("JWT_PRIVATE_KEY_FILE not set, deriving the Ed25519 signing key from JWT_SECRET")
+		seed := sha256.Sum256([]byte(jwtSecretKeyLabel + secret))
+		signer = ed25519.NewKeyFromSeed(seed[:])
+	} else if EphemeralKeysAllowed() {
+		// Development: token tidak valid lagi setelah restart (refresh token tetap jalan)
+		log.Println("ALLOW_EPHEMERAL_KEYS is set, using an ephemeral Ed25519 signing key")
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = priv
+	} else {
+		return nil, ErrNoSigningKey
+	}
+
+	signingKey, err := newJWTKey(os.Getenv("JWT_KEY_ID"), signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	set.signer = signer
+	set.signingKey = signingKey
+	set.add(signingKey)
+
+	// Key lama (rotasi) atau key baru yang dipublish lebih dulu di JWKS
+	for _, entry := range strings.Split(os.Getenv("JWT_PUBLIC_KEY_FILES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, path := "", entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			kid, path = entry[:i], entry[i+1:]
+		}
+
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read verification key %s: %w", path, err)
+		}
+		public, err := parsePublicKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse verification key %s: %w", path, err)
+		}
+		key, err := newJWTKey(kid, public)
+		if err != nil {
+			return nil, err
+		}
+		set.add(key)
+	}
+
+	return set, nil
+}
+
+func (s *keySet) add(key jwtKey) {
+	if _, exists := s.verify[key.id]; exists {
+		return
+	}
+	s.verify[key.id] = key
+	s.order = append(s.order, key.id)
+}
+
+func newJWTKey(kid string, public crypto.PublicKey) (jwtKey, error) {
+	var method jwt.SigningMethod
+	switch public.(type) {
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return jwtKey{}, fmt.Errorf("unsupported key type %T (use RSA or Ed25519)", public)
+	}
+
+	if kid == "" {
+		kid = keyThumbprint(public)
+	}
+	return jwtKey{id: kid, method: method, public: public}, nil
+}
+
+func parsePrivateKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+func parsePublicKey(pemBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func jwtIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultJWTIssuer
+}
+
+func jwtAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return defaultJWTAudience
+}
+
+// Tanda tangani claims dengan key aktif, sekalian isi claim standar (iss, aud, iat, ...)
+func SignAccessToken(userID uint, familyID string, expiresAt time.Time) (string, error) {
+	if err := LoadSigningKeys(); err != nil {
+		return "", err
+	}
+
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := AccessClaims{
+		UserID:    userID,
+		SessionID: familyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtIssuer(),
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Audience:  jwt.ClaimStrings{jwtAudience()},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+	}
+
+	token := jwt.NewWithClaims(keys.signingKey.method, claims)
+	token.Header["kid"] = keys.signingKey.id
+	return token.SignedString(keys.signer)
+}
+
+// Verifikasi signature (berdasarkan kid) dan claim standar
+func ParseAccessToken(raw string) (*jwt.Token, *AccessClaims, error) {
+	if err := LoadSigningKeys(); err != nil {
+		return nil, nil, err
+	}
+
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.verify[kid]
+		if !ok {
+			return nil, ErrUnknownSigningKey
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.public, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(jwtIssuer()),
+		jwt.WithAudience(jwtAudience()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if claims.UserID == 0 || claims.SessionID == "" {
+		return nil, nil, jwt.ErrTokenInvalidClaims
+	}
+	return token, claims, nil
+}
+
+// Semua key verifikasi dalam format JSON Web Key Set
+func JWKS() (map[string]any, error) {
+	if err := LoadSigningKeys(); err != nil {
+		return nil, err
+	}
+
+	jwks := make([]map[string]string, 0, len(keys.order))
+	for _, kid := range keys.order {
+		key := keys.verify[kid]
+		jwk := publicJWK(key.public)
+		jwk["kid"] = key.id
+		jwk["use"] = "sig"
+		jwk["alg"] = key.method.Alg()
+		jwks = append(jwks, jwk)
+	}
+	return map[string]any{"keys": jwks}, nil
+}
+
+func publicJWK(public crypto.PublicKey) map[string]string {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(key),
+		}
+	}
+	return map[string]string{}
+}
+
+// kid stabil dari public key (RFC 7638 thumbprint)
+func keyThumbprint(public crypto.PublicKey) string {
+	// json.Marshal mengurutkan key map, sesuai urutan leksikografis yang diminta RFC 7638
+	canonical, _ := json.Marshal(publicJWK(public))
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,56 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoadKeySetFromJWTSecret(t *testing.T) {
+	t.Setenv("JWT_PRIVATE_KEY_FILE", "")
+	t.Setenv("JWT_PUBLIC_KEY_FILES", "")
+	t.Setenv("JWT_KEY_ID", "")
+	t.Setenv("ALLOW_EPHEMERAL_KEYS", "")
+	t.Setenv("JWT_SECRET", "existing-deployment-secret")
+
+	first, err := loadKeySet()
+	if err != nil {
+		t.Fatalf("loadKeySet: %v", err)
+	}
+	second, err := loadKeySet()
+	if err != nil {
+		t.Fatalf("loadKeySet: %v", err)
+	}
+
+	// Key sama setelah restart / di replica lain
+	if first.signingKey.id != second.signingKey.id {
+		t.Errorf("kid changed between loads: %s vs %s", first.signingKey.id, second.signingKey.id)
+	}
+	if first.signingKey.method.Alg() != "EdDSA" {
+		t.Errorf("alg = %s, want EdDSA", first.signingKey.method.Alg())
+	}
+
+	t.Setenv("JWT_SECRET", "another-secret")
+	other, err := loadKeySet()
+	if err != nil {
+		t.Fatalf("loadKeySet: %v", err)
+	}
+	if other.signingKey.id == first.signingKey.id {
+		t.Error("different JWT_SECRET produced the same key")
+	}
+}
+
+func TestLoadKeySetRequiresKey(t *testing.T) {
+	t.Setenv("JWT_PRIVATE_KEY_FILE", "")
+	t.Setenv("JWT_PUBLIC_KEY_FILES", "")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("ALLOW_EPHEMERAL_KEYS", "")
+
+	if _, err := loadKeySet(); !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("loadKeySet error = %v, want ErrNoSigningKey", err)
+	}
+
+	t.Setenv("ALLOW_EPHEMERAL_KEYS", "true")
+	if _, err := loadKeySet(); err != nil {
+		t.Fatalf("loadKeySet with ALLOW_EPHEMERAL_KEYS: %v", err)
+	}
+}
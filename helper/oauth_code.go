@@ -0,0 +1,51 @@
+package helper
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"gorm.io/gorm/clause"
+)
+
+// Kode login OAuth hanya perlu hidup selama redirect ke frontend + satu POST
+const oauthLoginCodeTTL = time.Minute
+
+var ErrInvalidOAuthLoginCode = errors.New("invalid or expired login code")
+
+// Simpan kode sekali pakai yang ditukar frontend dengan token, jadi token tidak pernah ada di URL redirect
+func CreateOAuthLoginCode(userID uint) (string, error) {
+	rawCode, err := RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	// Bersihkan kode lama yang tidak pernah ditukar
+	config.DB.Where("expires_at < ?", time.Now()).Delete(&models.OAuthLoginCode{})
+
+	code := models.OAuthLoginCode{
+		UserID:    userID,
+		CodeHash:  HashToken(rawCode),
+		ExpiresAt: time.Now().Add(oauthLoginCodeTTL),
+	}
+	if err := config.DB.Create(&code).Error; err != nil {
+		return "", err
+	}
+	return rawCode, nil
+}
+
+// Hapus kode & kembalikan user-nya. Delete dalam satu statement, jadi kode hanya bisa ditukar sekali
+func ConsumeOAuthLoginCode(rawCode string) (uint, error) {
+	var code models.OAuthLoginCode
+	result := config.DB.Clauses(clause.Returning{}).
+		Where("code_hash = ? AND expires_at > ?", HashToken(rawCode), time.Now()).
+		Delete(&code)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 || code.UserID == 0 {
+		return 0, ErrInvalidOAuthLoginCode
+	}
+	return code.UserID, nil
+}
@@ -0,0 +1,79 @@
+package helper
+
+import (
+	"os"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+)
+
+// Hari pertama dalam minggu yang bisa dipilih user
+var WeekStarts = map[string]time.Weekday{
+	"sunday":   time.Sunday,
+	"monday":   time.Monday,
+	"saturday": time.Saturday,
+}
+
+// Time zone default untuk user yang belum mengatur preferences (DEFAULT_TIMEZONE, default UTC)
+func DefaultTimeZone() string {
+	if tz := os.Getenv("DEFAULT_TIMEZONE"); tz != "" {
+		if _, err := time.LoadLocation(tz); err == nil {
+			return tz
+		}
+	}
+	return "UTC"
+}
+
+// Preferences default sampai user mengubahnya
+func DefaultPreferences(userID uint) models.UserPreference {
+	return models.UserPreference{
+		UserID:          userID,
+		TimeZone:        DefaultTimeZone(),
+		Locale:          "en",
+		WeekStart:       "monday",
+		DefaultPriority: "medium",
+		DefaultStatus:   "todo",
+	}
+}
+
+// Preferences tersimpan, atau default kalau belum ada
+func GetPreferences(userID uint) models.UserPreference {
+	var pref models.UserPreference
+	if err := config.DB.Where("user_id = ?", userID).First(&pref).Error; err != nil {
+		return DefaultPreferences(userID)
+	}
+	return pref
+}
+
+// Time zone dari preferences, fallback ke UTC kalau tidak valid
+func Location(pref models.UserPreference) *time.Location {
+	loc, err := time.LoadLocation(pref.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Time zone yang dipakai untuk membaca tanggal dari user
+func UserLocation(userID uint) *time.Location {
+	return Location(GetPreferences(userID))
+}
+
+// Tengah malam di hari yang sama dengan t (di time zone loc)
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// Tengah malam di hari pertama minggu t (di time zone loc, sesuai week start user)
+func StartOfWeek(t time.Time, loc *time.Location, weekStart string) time.Time {
+	first, ok := WeekStarts[weekStart]
+	if !ok {
+		first = time.Monday
+	}
+
+	day := StartOfDay(t, loc)
+	offset := (int(day.Weekday()) - int(first) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"gorm.io/gorm"
+)
+
+var ErrSessionRevoked = errors.New("session has been revoked")
+
+// Last seen cukup di-update paling sering sekali per menit
+const sessionTouchInterval = time.Minute
+
+// Cek session dari access token masih aktif, sekalian catat last seen
+func TouchSession(familyID string, userID uint) error {
+	var session models.Session
+	if err := config.DB.Where("family_id = ? AND user_id = ?", familyID, userID).First(&session).Error; err != nil {
+		return ErrSessionRevoked
+	}
+
+	if session.RevokedAt != nil {
+		return ErrSessionRevoked
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		config.DB.Model(&session).UpdateColumn("last_seen_at", time.Now())
+	}
+	return nil
+}
+
+// Cabut session beserta seluruh refresh token family-nya
+func RevokeSession(familyID string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.Session{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+	})
+}
@@ -0,0 +1,177 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// TTL access token dari ACCESS_TOKEN_TTL_MINUTES (default 15 menit)
+func accessTokenTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("ACCESS_TOKEN_TTL_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return defaultAccessTokenTTL
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// TTL refresh token dari REFRESH_TOKEN_TTL_DAYS (default 30 hari)
+func refreshTokenTTL() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_TTL_DAYS"))
+	if err != nil || days <= 0 {
+		return defaultRefreshTokenTTL
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// n byte acak (CSPRNG) di-encode base64 URL-safe
+func RandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Token opaque disimpan di DB sebagai hash SHA-256 (hex)
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Access token (JWT) berumur pendek yang terikat ke satu refresh token family
+func GenerateAccessToken(userID uint, familyID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(accessTokenTTL())
+	token, err := SignAccessToken(userID, familyID, expiresAt)
+	return token, expiresAt, err
+}
+
+func issueTokenPair(tx *gorm.DB, userID uint, familyID string) (models.TokenPair, *models.RefreshToken, error) {
+	accessToken, accessExpiresAt, err := GenerateAccessToken(userID, familyID)
+	if err != nil {
+		return models.TokenPair{}, nil, err
+	}
+
+	rawRefresh, err := RandomToken(32)
+	if err != nil {
+		return models.TokenPair{}, nil, err
+	}
+
+	refresh := models.RefreshToken{
+		UserID:    userID,
+		TokenHash: HashToken(rawRefresh),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(refreshTokenTTL()),
+	}
+	if err := tx.Create(&refresh).Error; err != nil {
+		return models.TokenPair{}, nil, err
+	}
+
+	return models.TokenPair{
+		AccessToken:      accessToken,
+		ExpiresAt:        accessExpiresAt.Unix(),
+		RefreshToken:     rawRefresh,
+		RefreshExpiresAt: refresh.ExpiresAt.Unix(),
+	}, &refresh, nil
+}
+
+// Buat session & refresh token family baru (satu per login)
+func IssueTokenPair(userID uint, userAgent, ip string) (models.TokenPair, error) {
+	familyID, err := RandomToken(16)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	var pair models.TokenPair
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		session := models.Session{
+			UserID:     userID,
+			FamilyID:   familyID,
+			UserAgent:  userAgent,
+			IP:         ip,
+			LastSeenAt: time.Now(),
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		pair, _, err = issueTokenPair(tx, userID, familyID)
+		return err
+	})
+	return pair, err
+}
+
+// Tukar refresh token dengan pasangan baru. Token yang sudah pernah di-rotate
+// dipakai lagi = dicuri, jadi seluruh family-nya dicabut
+func RotateRefreshToken(rawRefresh, userAgent, ip string) (models.TokenPair, error) {
+	var current models.RefreshToken
+	if err := config.DB.Where("token_hash = ?", HashToken(rawRefresh)).First(&current).Error; err != nil {
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	if current.RevokedAt != nil {
+		if current.ReplacedByID != nil {
+			RevokeSession(current.FamilyID)
+			return models.TokenPair{}, ErrRefreshTokenReused
+		}
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	var pair models.TokenPair
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Hanya satu request yang boleh me-rotate token ini
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var next *models.RefreshToken
+		var err error
+		pair, next, err = issueTokenPair(tx, current.UserID, current.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.RefreshToken{}).Where("id = ?", current.ID).Update("replaced_by_id", next.ID).Error
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		RevokeSession(current.FamilyID)
+		return pair, err
+	}
+	if err == nil {
+		config.DB.Model(&models.Session{}).Where("family_id = ?", current.FamilyID).
+			Updates(map[string]any{"last_seen_at": time.Now(), "user_agent": userAgent, "ip": ip})
+	}
+	return pair, err
+}
@@ -0,0 +1,120 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+)
+
+// Parameter TOTP (RFC 6238) yang didukung semua authenticator app
+const (
+	totpIssuer = "Koto Todolist"
+	totpDigits = 6
+	totpPeriod = 30
+	totpSkew   = 1 // toleransi 1 step (30 detik) sebelum/sesudah
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Secret TOTP acak 160-bit (base32)
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// URI otpauth:// yang ditampilkan client sebagai QR code
+func TOTPURI(secret, account string) string {
+	label := url.PathEscape(totpIssuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// Cek kode terhadap secret dan kembalikan time step yang cocok. Step <= lastStep
+// ditolak supaya kode yang sama tidak bisa dipakai ulang
+func VerifyTOTP(secret, code string, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := time.Now().Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// n recovery code sekali pakai dengan format xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	codes := make([]string, n)
+	for i := range codes {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		for j := range buf {
+			buf[j] = alphabet[int(buf[j])%len(alphabet)]
+		}
+		codes[i] = string(buf[:5]) + "-" + string(buf[5:])
+	}
+	return codes, nil
+}
+
+// Challenge 2FA berlaku 5 menit setelah password benar
+const twoFactorChallengeTTL = 5 * time.Minute
+
+// Simpan login yang menunggu kode TOTP / recovery code sebelum token diberikan
+func CreateTwoFactorChallenge(userID uint) (string, time.Time, error) {
+	rawToken, err := RandomToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	challenge := models.TwoFactorChallenge{
+		UserID:    userID,
+		TokenHash: HashToken(rawToken),
+		ExpiresAt: time.Now().Add(twoFactorChallengeTTL),
+	}
+	if err := config.DB.Create(&challenge).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	return rawToken, challenge.ExpiresAt, nil
+}
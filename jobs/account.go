@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"gorm.io/gorm"
+)
+
+// Tabel milik user yang ikut dihapus (FK lama di database belum tentu ON DELETE CASCADE)
+var userOwnedTables = []any{
+	&models.RefreshToken{},
+	&models.Session{},
+	&models.RecoveryCode{},
+	&models.TwoFactorChallenge{},
+	&models.EmailVerification{},
+	&models.Identity{},
+	&models.APIToken{},
+	&models.AccountDeletionCode{},
+	&models.EmailChange{},
+	&models.UserPreference{},
+	&models.OAuthLoginCode{},
+	&models.TaskComment{},
+	&models.TaskActivity{},
+	&models.Category{},
+}
+
+// Hapus permanen user beserta seluruh datanya dalam satu transaksi, lalu file-nya di storage
+func HardDeleteUser(userID uint) error {
+	var user models.User
+	if err := config.DB.Select("id, avatar_key").First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	var attachmentKeys []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var taskIDs []uint
+		if err := tx.Unscoped().Model(&models.Task{}).Where("user_id = ?", userID).Pluck("id", &taskIDs).Error; err != nil {
+			return err
+		}
+
+		if len(taskIDs) > 0 {
+			var err error
+			if attachmentKeys, err = helper.DeleteTaskAttachments(tx, taskIDs); err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("task_id IN ?", taskIDs).Delete(&models.TaskReminder{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("task_id IN ?", taskIDs).Delete(&models.Subtask{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Task{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, table := range userOwnedTables {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Delete(&models.User{}, userID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	helper.DeleteAttachmentObjects(attachmentKeys)
+	if err := helper.DeleteAvatarObjects(user.AvatarKey); err != nil {
+		log.Printf("Failed to delete avatar of account %d: %v", userID, err)
+	}
+	return nil
+}
+
+// Hapus akun yang masa tenggang hapus akunnya sudah lewat
+func PurgeScheduledAccountDeletions() {
+	var userIDs []uint
+	if err := config.DB.Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).
+		Pluck("id", &userIDs).Error; err != nil {
+		log.Println("Failed to load scheduled account deletions:", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		if err := HardDeleteUser(userID); err != nil {
+			log.Printf("Failed to delete account %d: %v", userID, err)
+			continue
+		}
+		log.Printf("Account %d deleted permanently", userID)
+	}
+}
+
+// Jalankan penghapusan akun terjadwal secara berkala di background
+func StartAccountDeletionPurger(interval time.Duration) {
+	go func() {
+		PurgeScheduledAccountDeletions()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			PurgeScheduledAccountDeletions()
+		}
+	}()
+}
@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/ratelimit"
+)
+
+// Bersihkan key rate limit lama dari in-memory store (kalau store default dipakai)
+func StartRateLimitCleanup(interval, window time.Duration) {
+	store, ok := ratelimit.DefaultStore.(*ratelimit.MemoryStore)
+	if !ok {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			store.Cleanup(window)
+		}
+	}()
+}
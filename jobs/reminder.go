@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"gorm.io/gorm/clause"
+)
+
+// Default reminder: 1 hari sebelum dan saat due date
+var defaultReminderOffsets = []int64{24 * 60, 0}
+
+const (
+	// Batas maksimal offset reminder (sama dengan validasi di controller)
+	maxReminderLookahead = 30 * 24 * time.Hour
+	// Reminder "sudah lewat due date" masih dikirim sampai 1 hari setelahnya
+	overdueReminderWindow = 24 * time.Hour
+)
+
+// Reminder offset jatuh tempo: sudah lewat waktunya tapi belum lewat jendela kirim
+func reminderDue(now, dueDate time.Time, offset int64) bool {
+	remindAt := dueDate.Add(-time.Duration(offset) * time.Minute)
+	if now.Before(remindAt) {
+		return false
+	}
+	if offset == 0 {
+		return now.Before(dueDate.Add(overdueReminderWindow))
+	}
+	return now.Before(dueDate)
+}
+
+// Klaim reminder di database dulu supaya tidak pernah terkirim dua kali
+func claimReminder(task models.Task, offset int64, now time.Time) (*models.TaskReminder, bool) {
+	reminder := models.TaskReminder{
+		TaskID:  task.ID,
+		Offset:  offset,
+		DueDate: *task.DueDate,
+		SentAt:  now,
+	}
+
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminder)
+	if result.Error != nil {
+		log.Println("Failed to claim reminder:", result.Error)
+		return nil, false
+	}
+	return &reminder, result.RowsAffected > 0
+}
+
+func reminderEmailBody(task models.Task, user models.User, loc *time.Location, now time.Time) string {
+	headline := fmt.Sprintf("Your task is due in %s", time.Until(*task.DueDate).Round(time.Minute))
+	if !now.Before(*task.DueDate) {
+		headline = "Your task is now due"
+	}
+
+	return fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<body style="font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px;">
+		<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; padding: 20px; border-radius: 8px;">
+			<h2 style="color: #2c3e50;">%s</h2>
+			<p>Hello %s,</p>
+			<p><strong>%s</strong></p>
+			<p>%s</p>
+			<p>Due: %s</p>
+			<p style="font-size: 12px; color: #7f8c8d;">&copy; %d Koto Todolist. All rights reserved.</p>
+		</div>
+	</body>
+	</html>
+	`, headline, html.EscapeString(user.Name), html.EscapeString(task.Title), html.EscapeString(task.ShortDesc),
+		task.DueDate.In(loc).Format("Mon, 02 Jan 2006 15:04 MST"), now.Year())
+}
+
+// Kirim email reminder untuk task yang mendekati / melewati due date
+func SendDueReminders() {
+	now := time.Now()
+
+	var tasks []models.Task
+	if err := config.DB.
+		Where("status <> ? AND due_date IS NOT NULL AND due_date > ? AND due_date <= ?",
+			"done", now.Add(-overdueReminderWindow), now.Add(maxReminderLookahead)).
+		Where("reminder_offsets IS NULL OR cardinality(reminder_offsets) > 0").
+		// Akun yang dinonaktifkan admin atau dijadwalkan dihapus tidak dikirimi reminder
+		Where("user_id IN (?)", config.DB.Model(&models.User{}).Select("id").
+			Where("disabled_at IS NULL AND deletion_scheduled_at IS NULL")).
+		Find(&tasks).Error; err != nil {
+		log.Println("Failed to load tasks for reminders:", err)
+		return
+	}
+
+	users := map[uint]models.User{}
+	locations := map[uint]*time.Location{}
+	for _, task := range tasks {
+		offsets := []int64(task.ReminderOffsets)
+		if offsets == nil {
+			offsets = defaultReminderOffsets
+		}
+
+		for _, offset := range offsets {
+			if !reminderDue(now, *task.DueDate, offset) {
+				continue
+			}
+
+			reminder, claimed := claimReminder(task, offset, now)
+			if !claimed {
+				continue
+			}
+
+			user, ok := users[task.UserID]
+			if !ok {
+				if err := config.DB.Select("id, name, email").First(&user, task.UserID).Error; err != nil {
+					log.Println("Failed to load user for reminder:", err)
+					config.DB.Delete(reminder)
+					continue
+				}
+				users[task.UserID] = user
+				locations[task.UserID] = helper.UserLocation(task.UserID)
+			}
+
+			if err := helper.SendEmail(user.Email, "Reminder: "+task.Title, reminderEmailBody(task, user, locations[task.UserID], now)); err != nil {
+				// Lepas klaim supaya dicoba lagi di tick berikutnya
+				log.Println("Failed to send reminder email:", err)
+				config.DB.Delete(reminder)
+			}
+		}
+	}
+}
+
+// Jalankan scheduler reminder secara berkala di background
+func StartReminderScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			SendDueReminders()
+		}
+	}()
+}
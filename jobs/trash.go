@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"gorm.io/gorm"
+)
+
+const defaultTrashRetentionDays = 30
+
+// Retention trash diambil dari TRASH_RETENTION_DAYS (default 30 hari)
+func trashRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Hapus permanen task & category yang sudah di trash lebih lama dari retention
+func PurgeExpiredTrash() {
+	cutoff := time.Now().Add(-trashRetention())
+
+	// Task dihapus bersama attachment-nya, file di storage dibersihkan setelah commit
+	var purgedTasks int64
+	var attachmentKeys []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var tasks []models.Task
+		if err := tx.Unscoped().Select("id, user_id").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Find(&tasks).Error; err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return nil
+		}
+
+		taskIDs := make([]uint, 0, len(tasks))
+		activities := make([]models.TaskActivity, 0, len(tasks))
+		for _, task := range tasks {
+			taskIDs = append(taskIDs, task.ID)
+			activities = append(activities, helper.TaskEvent(task.ID, task.UserID, helper.ActivityPurged))
+		}
+
+		var err error
+		if attachmentKeys, err = helper.DeleteTaskAttachments(tx, taskIDs); err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("id IN ?", taskIDs).Delete(&models.Task{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purgedTasks = result.RowsAffected
+
+		// Riwayat task tetap disimpan, ditutup dengan event purged
+		return helper.RecordTaskActivities(tx, activities)
+	})
+	if err != nil {
+		log.Println("Failed to purge expired tasks:", err)
+	} else {
+		helper.DeleteAttachmentObjects(attachmentKeys)
+	}
+
+	cats := config.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.Category{})
+	if cats.Error != nil {
+		log.Println("Failed to purge expired categories:", cats.Error)
+	}
+
+	if purgedTasks > 0 || cats.RowsAffected > 0 {
+		log.Printf("Trash purged: %d tasks, %d categories", purgedTasks, cats.RowsAffected)
+	}
+}
+
+// Jalankan purge trash secara berkala di background
+func StartTrashPurger(interval time.Duration) {
+	go func() {
+		PurgeExpiredTrash()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			PurgeExpiredTrash()
+		}
+	}()
+}
@@ -1,21 +1,12 @@
 package middleware
 
 import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"slices"
 	"strings"
-	"time"
 
-	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
 	"github.com/MashuNakamura/todolist-backend/models"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 func Protected(c *fiber.Ctx) error {
@@ -31,14 +22,23 @@ func Protected(c *fiber.Ctx) error {
 
 	tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fiber.ErrUnauthorized
+	// Personal access token: user_id baru di-set oleh RequireScope di route yang mengizinkan
+	if strings.HasPrefix(tokenString, helper.APITokenPrefix) {
+		apiToken, err := helper.AuthenticateAPIToken(tokenString)
+		if err != nil {
+			return c.Status(401).JSON(models.Ret{
+				Success: false,
+				Message: "Invalid or Expired Token",
+				Error:   401,
+			})
 		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
 
-	if err != nil || !token.Valid {
+		c.Locals("api_token", apiToken)
+		return c.Next()
+	}
+
+	token, claims, err := helper.ParseAccessToken(tokenString)
+	if err != nil {
 		return c.Status(401).JSON(models.Ret{
 			Success: false,
 			Message: "Invalid or Expired Token",
@@ -46,85 +46,55 @@ func Protected(c *fiber.Ctx) error {
 		})
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	userID := claims.UserID
+
+	// Session harus masih aktif (belum logout / di-revoke dari device lain)
+	sid := claims.SessionID
+	if helper.TouchSession(sid, userID) != nil {
 		return c.Status(401).JSON(models.Ret{
 			Success: false,
-			Message: "Invalid Token Claims",
+			Message: "Session has been revoked",
 			Error:   401,
 		})
 	}
 
 	c.Locals("user", token)
-	c.Locals("user_id", uint(claims["user_id"].(float64)))
+	c.Locals("user_id", userID)
+	c.Locals("session_id", sid)
 	return c.Next()
 }
 
-func getGoogleConfig() *oauth2.Config {
-	return &oauth2.Config{
-		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-		Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
-		Endpoint:     google.Endpoint,
-	}
-}
-
-func GoogleLogin(c *fiber.Ctx) error {
-	config := getGoogleConfig()
-	url := config.AuthCodeURL("random-state")
-	return c.JSON(fiber.Map{
-		"url": url,
-	})
-}
-
-func GoogleCallback(c *fiber.Ctx) error {
-	code := c.Query("code")
-	googleConfig := getGoogleConfig()
-
-	token, err := googleConfig.Exchange(context.Background(), code)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to exchange token from Google"})
-	}
-
-	resp, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + token.AccessToken)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to get user info from Google"})
-	}
-	defer resp.Body.Close()
+// Personal access token hanya lolos kalau punya semua scope yang diminta.
+// Route tanpa RequireScope hanya bisa diakses dengan session login (JWT)
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		apiToken, ok := c.Locals("api_token").(*models.APIToken)
+		if !ok {
+			return c.Next()
+		}
 
-	userData, _ := io.ReadAll(resp.Body)
+		for _, scope := range scopes {
+			if !slices.Contains(apiToken.Scopes, scope) {
+				return c.Status(403).JSON(models.Ret{
+					Success: false,
+					Message: "Token is missing required scope: " + scope,
+					Error:   403,
+				})
+			}
+		}
 
-	var googleUser struct {
-		Email string `json:"email"`
-		Name  string `json:"name"`
-		ID    string `json:"id"`
+		c.Locals("user_id", apiToken.UserID)
+		return c.Next()
 	}
-	json.Unmarshal(userData, &googleUser)
+}
 
-	var user models.User
-	if err := config.DB.Where("email = ?", googleUser.Email).First(&user).Error; err != nil {
-		user = models.User{
-			Name:     googleUser.Name,
-			Email:    googleUser.Email,
-			Password: "",
-		}
-		if err := config.DB.Create(&user).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"message": "Failed to create user"})
-		}
+// User yang sedang login, dari JWT maupun personal access token
+func AuthUserID(c *fiber.Ctx) (uint, bool) {
+	if userID, ok := c.Locals("user_id").(uint); ok {
+		return userID, true
 	}
-
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
-
-	jwtToken, err := claims.SignedString([]byte(os.Getenv("JWT_SECRET")))
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"message": "Failed to generate token"})
+	if apiToken, ok := c.Locals("api_token").(*models.APIToken); ok {
+		return apiToken.UserID, true
 	}
-
-	frontendURL := os.Getenv("FRONTEND_URL")
-
-	return c.Redirect(fmt.Sprintf("%s/auth/google/callback?token=%s", frontendURL, jwtToken))
+	return 0, false
 }
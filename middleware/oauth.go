@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityLinkedElsewhere = errors.New("identity is linked to another account")
+	ErrAccountExists           = errors.New("an account with this email already exists")
+)
+
+// Cari user lewat identity (provider + subject). Kalau belum ada:
+//   - email belum terdaftar: buat user baru + identity
+//   - akun lama tanpa password & tanpa identity (user Google sebelum ada tabel identities),
+//     dan email sudah diverifikasi provider: link otomatis
+//   - selain itu user harus login dulu lalu link manual
+func findOrCreateOAuthUser(provider string, oauthUser OAuthUser) (models.User, error) {
+	var user models.User
+
+	var identity models.Identity
+	if err := config.DB.Where("provider = ? AND subject = ?", provider, oauthUser.Subject).First(&identity).Error; err == nil {
+		err := config.DB.First(&user, identity.UserID).Error
+		return user, err
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("email = ?", oauthUser.Email).First(&user).Error; err == nil {
+			var linked int64
+			tx.Model(&models.Identity{}).Where("user_id = ?", user.ID).Count(&linked)
+			if user.Password != "" || linked > 0 || !oauthUser.EmailVerified {
+				return ErrAccountExists
+			}
+		} else {
+			user = models.User{
+				Name:          oauthUser.Name,
+				Email:         oauthUser.Email,
+				Password:      "",
+				EmailVerified: oauthUser.EmailVerified,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.Identity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  oauthUser.Subject,
+			Email:    oauthUser.Email,
+		}).Error
+	})
+	return user, err
+}
+
+// Link identity ke akun yang sedang login
+func linkOAuthIdentity(provider string, userID uint, oauthUser OAuthUser) error {
+	var identity models.Identity
+	if err := config.DB.Where("provider = ? AND subject = ?", provider, oauthUser.Subject).First(&identity).Error; err == nil {
+		if identity.UserID != userID {
+			return ErrIdentityLinkedElsewhere
+		}
+		return nil
+	}
+
+	return config.DB.Create(&models.Identity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  oauthUser.Subject,
+		Email:    oauthUser.Email,
+	}).Error
+}
+
+// Selesaikan login OAuth: 2FA challenge atau token, lalu redirect ke frontend
+func completeOAuthLogin(c *fiber.Ctx, provider string, user models.User) error {
+	callbackURL := fmt.Sprintf("%s/auth/%s/callback", os.Getenv("FRONTEND_URL"), provider)
+
+	if err := helper.CheckAccountStatus(user); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Your account is not allowed to sign in: " + err.Error()})
+	}
+
+	// Sama seperti Login: EMAIL_VERIFICATION_MODE=login menolak akun yang belum verifikasi email
+	if !user.EmailVerified && helper.EmailVerificationMode() == helper.EmailVerificationLogin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Please verify your email before logging in"})
+	}
+
+	// 2FA aktif: frontend harus menyelesaikan challenge lewat /api/login/2fa
+	if user.TOTPEnabled {
+		challenge, _, err := helper.CreateTwoFactorChallenge(user.ID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"message": "Failed to create 2FA challenge"})
+		}
+		return c.Redirect(fmt.Sprintf("%s?challenge_token=%s", callbackURL, challenge))
+	}
+
+	// Token tidak pernah ditaruh di URL (history, log proxy, Referer): frontend menukar
+	// kode sekali pakai ini lewat POST /api/auth/exchange
+	code, err := helper.CreateOAuthLoginCode(user.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"message": "Failed to generate token"})
+	}
+
+	return c.Redirect(fmt.Sprintf("%s?code=%s", callbackURL, url.QueryEscape(code)))
+}
+
+// Mulai OAuth flow (login atau link) dan kembalikan URL provider
+func beginOAuth(c *fiber.Ctx, linkUserID uint) error {
+	provider, ok := GetOAuthProvider(c.Params("provider"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Unknown login provider"})
+	}
+
+	// ?redirect=true untuk langsung redirect (navigasi top-level, cookie state first-party).
+	// Default JSON {url} untuk XHR dari frontend: cookie state SameSite=None; Secure,
+	// jadi frontend wajib memanggil endpoint ini dengan credentials: "include"
+	redirect := c.QueryBool("redirect")
+
+	flow, err := startOAuthFlow(c, provider.Name(), linkUserID, !redirect)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"message": "Failed to start login"})
+	}
+
+	authURL := provider.Config().AuthCodeURL(flow.State, oauth2.S256ChallengeOption(flow.Verifier))
+
+	if redirect {
+		return c.Redirect(authURL)
+	}
+
+	return c.JSON(fiber.Map{
+		"url": authURL,
+	})
+}
+
+// API Login OAuth (google, github, oidc, ...)
+func OAuthLogin(c *fiber.Ctx) error {
+	return beginOAuth(c, 0)
+}
+
+// API Link OAuth Identity ke akun yang sedang login (Protected)
+func OAuthLink(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(401).JSON(models.Ret{
+			Success: false,
+			Message: "Unauthorized: Invalid User Session",
+			Error:   401,
+		})
+	}
+	return beginOAuth(c, userID)
+}
+
+// API Callback OAuth (google, github, oidc, ...)
+func OAuthCallback(c *fiber.Ctx) error {
+	provider, ok := GetOAuthProvider(c.Params("provider"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Unknown login provider"})
+	}
+
+	flow, err := finishOAuthFlow(c, provider.Name())
+	if err != nil {
+		message := "Invalid OAuth state, please try logging in again"
+		if errors.Is(err, ErrOAuthStateExpired) {
+			message = "Login session expired, please try logging in again"
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": message})
+	}
+
+	if errorCode := c.Query("error"); errorCode != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Login was cancelled: " + errorCode})
+	}
+
+	ctx := context.Background()
+	token, err := provider.Config().Exchange(ctx, c.Query("code"), oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to exchange token from " + provider.Name()})
+	}
+
+	oauthUser, err := provider.FetchUser(ctx, token)
+	if err != nil || oauthUser.Subject == "" {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to get user info from " + provider.Name()})
+	}
+
+	// Flow link: tambahkan identity ke akun yang memulai flow
+	if flow.LinkUserID != 0 {
+		if err := linkOAuthIdentity(provider.Name(), flow.LinkUserID, oauthUser); err != nil {
+			if errors.Is(err, ErrIdentityLinkedElsewhere) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "This " + provider.Name() + " account is already linked to another user"})
+			}
+			return c.Status(500).JSON(fiber.Map{"message": "Failed to link account"})
+		}
+		return c.Redirect(fmt.Sprintf("%s/auth/%s/callback?linked=true", os.Getenv("FRONTEND_URL"), provider.Name()))
+	}
+
+	if oauthUser.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Your " + provider.Name() + " account has no email address"})
+	}
+
+	user, err := findOrCreateOAuthUser(provider.Name(), oauthUser)
+	if err != nil {
+		if errors.Is(err, ErrAccountExists) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "An account with this email already exists. Sign in and link your " + provider.Name() + " account from your profile"})
+		}
+		return c.Status(500).JSON(fiber.Map{"message": "Failed to create user"})
+	}
+
+	return completeOAuthLogin(c, provider.Name(), user)
+}
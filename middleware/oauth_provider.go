@@ -0,0 +1,293 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+	"golang.org/x/oauth2/google"
+)
+
+// OAuthUser adalah profil user yang dinormalisasi dari provider manapun
+type OAuthUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Satu provider login OAuth2 / OIDC
+type OAuthProvider interface {
+	Name() string
+	Config() *oauth2.Config
+	FetchUser(ctx context.Context, token *oauth2.Token) (OAuthUser, error)
+}
+
+var oauthHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// GET JSON dengan access token sebagai Bearer
+func fetchOAuthJSON(ctx context.Context, url string, token *oauth2.Token, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oauthHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// 1. Google
+type googleProvider struct {
+	config *oauth2.Config
+}
+
+func (p *googleProvider) Name() string           { return "google" }
+func (p *googleProvider) Config() *oauth2.Config { return p.config }
+
+func (p *googleProvider) FetchUser(ctx context.Context, token *oauth2.Token) (OAuthUser, error) {
+	var googleUser struct {
+		ID            string `json:"id"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Name          string `json:"name"`
+	}
+	if err := fetchOAuthJSON(ctx, "https://www.googleapis.com/oauth2/v2/userinfo", token, &googleUser); err != nil {
+		return OAuthUser{}, err
+	}
+
+	return OAuthUser{
+		Subject:       googleUser.ID,
+		Email:         googleUser.Email,
+		EmailVerified: googleUser.VerifiedEmail,
+		Name:          googleUser.Name,
+	}, nil
+}
+
+// 2. GitHub
+type githubProvider struct {
+	config *oauth2.Config
+}
+
+func (p *githubProvider) Name() string           { return "github" }
+func (p *githubProvider) Config() *oauth2.Config { return p.config }
+
+func (p *githubProvider) FetchUser(ctx context.Context, token *oauth2.Token) (OAuthUser, error) {
+	var githubUser struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := fetchOAuthJSON(ctx, "https://api.github.com/user", token, &githubUser); err != nil {
+		return OAuthUser{}, err
+	}
+
+	// Email publik bisa kosong, ambil primary email yang sudah verified
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := fetchOAuthJSON(ctx, "https://api.github.com/user/emails", token, &emails); err != nil {
+		return OAuthUser{}, err
+	}
+
+	user := OAuthUser{
+		Subject: strconv.FormatInt(githubUser.ID, 10),
+		Name:    githubUser.Name,
+	}
+	if user.Name == "" {
+		user.Name = githubUser.Login
+	}
+	for _, email := range emails {
+		if email.Primary {
+			user.Email = email.Email
+			user.EmailVerified = email.Verified
+		}
+	}
+	return user, nil
+}
+
+// 3. Generic OIDC (endpoint diambil dari discovery document issuer)
+type oidcProvider struct {
+	name        string
+	config      *oauth2.Config
+	userInfoURL string
+}
+
+func (p *oidcProvider) Name() string           { return p.name }
+func (p *oidcProvider) Config() *oauth2.Config { return p.config }
+
+func (p *oidcProvider) FetchUser(ctx context.Context, token *oauth2.Token) (OAuthUser, error) {
+	var claims struct {
+		Subject       string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified any    `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := fetchOAuthJSON(ctx, p.userInfoURL, token, &claims); err != nil {
+		return OAuthUser{}, err
+	}
+
+	// Beberapa issuer mengirim email_verified sebagai string "true"
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+
+	return OAuthUser{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: verified,
+		Name:          claims.Name,
+	}, nil
+}
+
+func discoverOIDCProvider(name, issuer string) (*oidcProvider, error) {
+	discoveryURL := strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+
+	resp, err := oauthHTTPClient.Get(discoveryURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %d", discoveryURL, resp.StatusCode)
+	}
+
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	// Issuer wajib sama dengan OIDC_ISSUER_URL (OpenID Connect Discovery 1.0, bagian 4.3)
+	if strings.TrimRight(doc.Issuer, "/") != strings.TrimRight(issuer, "/") {
+		return nil, fmt.Errorf("discovery document issuer %q does not match OIDC_ISSUER_URL %q", doc.Issuer, issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.UserInfoEndpoint == "" {
+		return nil, fmt.Errorf("discovery document of %s is missing endpoints", issuer)
+	}
+
+	scopes := []string{"openid", "email", "profile"}
+	if value := os.Getenv("OIDC_SCOPES"); value != "" {
+		scopes = strings.Fields(strings.ReplaceAll(value, ",", " "))
+	}
+
+	return &oidcProvider{
+		name: name,
+		config: &oauth2.Config{
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:       scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  doc.AuthorizationEndpoint,
+				TokenURL: doc.TokenEndpoint,
+			},
+		},
+		userInfoURL: doc.UserInfoEndpoint,
+	}, nil
+}
+
+// Discovery dicoba ulang beberapa kali supaya gangguan jaringan sesaat saat deploy tidak fatal
+const (
+	oidcDiscoveryAttempts = 4
+	oidcDiscoveryBackoff  = 2 * time.Second
+)
+
+func discoverOIDCProviderWithRetry(name, issuer string) (*oidcProvider, error) {
+	var err error
+	for attempt := 1; attempt <= oidcDiscoveryAttempts; attempt++ {
+		var provider *oidcProvider
+		if provider, err = discoverOIDCProvider(name, issuer); err == nil {
+			return provider, nil
+		}
+		if attempt < oidcDiscoveryAttempts {
+			log.Printf("OIDC discovery failed (attempt %d/%d): %v", attempt, oidcDiscoveryAttempts, err)
+			time.Sleep(time.Duration(attempt) * oidcDiscoveryBackoff)
+		}
+	}
+	return nil, err
+}
+
+var (
+	oauthProviders     map[string]OAuthProvider
+	oauthProvidersErr  error
+	oauthProvidersOnce sync.Once
+)
+
+// Konfigurasi provider dari environment variable (termasuk OIDC discovery). Dipanggil saat
+// startup: provider yang dikonfigurasi tapi gagal di-load menghentikan server, bukan hilang diam-diam
+func LoadOAuthProviders() error {
+	oauthProvidersOnce.Do(func() {
+		oauthProviders = map[string]OAuthProvider{}
+
+		if os.Getenv("GOOGLE_CLIENT_ID") != "" {
+			oauthProviders["google"] = &googleProvider{config: &oauth2.Config{
+				ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+				ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+				RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+				Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
+				Endpoint:     google.Endpoint,
+			}}
+		}
+
+		if os.Getenv("GITHUB_CLIENT_ID") != "" {
+			oauthProviders["github"] = &githubProvider{config: &oauth2.Config{
+				ClientID:     os.Getenv("GITHUB_CLIENT_ID"),
+				ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+				RedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
+				Scopes:       []string{"read:user", "user:email"},
+				Endpoint:     github.Endpoint,
+			}}
+		}
+
+		if issuer := os.Getenv("OIDC_ISSUER_URL"); issuer != "" {
+			name := os.Getenv("OIDC_PROVIDER_NAME")
+			if name == "" {
+				name = "oidc"
+			}
+
+			provider, err := discoverOIDCProviderWithRetry(name, issuer)
+			if err != nil {
+				oauthProvidersErr = fmt.Errorf("load OIDC provider %s: %w", name, err)
+				return
+			}
+			oauthProviders[name] = provider
+		}
+	})
+	return oauthProvidersErr
+}
+
+// Provider yang dikonfigurasi berdasarkan nama
+func GetOAuthProvider(name string) (OAuthProvider, bool) {
+	LoadOAuthProviders()
+	provider, ok := oauthProviders[name]
+	return provider, ok
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscoverOIDCProviderIssuer(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuer  func(serverURL string) string
+		wantErr bool
+	}{
+		{"matching issuer", func(u string) string { return u }, false},
+		{"trailing slash", func(u string) string { return u + "/" }, false},
+		{"different issuer", func(string) string { return "https://evil.example.com" }, true},
+		{"missing issuer", func(string) string { return "" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(map[string]string{
+					"issuer":                 tt.issuer(server.URL),
+					"authorization_endpoint": server.URL + "/authorize",
+					"token_endpoint":         server.URL + "/token",
+					"userinfo_endpoint":      server.URL + "/userinfo",
+				})
+			}))
+			defer server.Close()
+
+			provider, err := discoverOIDCProvider("oidc", server.URL)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected issuer mismatch error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("discoverOIDCProvider: %v", err)
+			}
+			if provider == nil {
+				t.Fatal("expected provider")
+			}
+		})
+	}
+}
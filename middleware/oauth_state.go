@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oauthStateCookie = "oauth_state"
+	oauthCookiePath  = "/api/auth"
+	oauthStateTTL    = 10 * time.Minute
+)
+
+var (
+	ErrOAuthStateMissing  = errors.New("missing OAuth state")
+	ErrOAuthStateMismatch = errors.New("OAuth state mismatch")
+	ErrOAuthStateExpired  = errors.New("OAuth state expired")
+)
+
+// oauthFlow adalah data login OAuth yang disimpan di cookie bertanda tangan
+type oauthFlow struct {
+	Provider   string `json:"p"`
+	State      string `json:"s"`
+	Verifier   string `json:"v"`
+	Expires    int64  `json:"e"`
+	LinkUserID uint   `json:"u,omitempty"` // > 0 kalau flow untuk link identity ke akun yang sedang login
+}
+
+var (
+	stateSecretOnce sync.Once
+	stateSecret     []byte
+	stateSecretErr  error
+)
+
+// Load secret untuk tanda tangan cookie state OAuth, panggil saat startup supaya error
+// langsung ketahuan. Secret harus sama di semua replica, kalau tidak callback yang
+// masuk ke replica lain akan ditolak
+func LoadOAuthStateSecret() error {
+	stateSecretOnce.Do(func() {
+		if secret := os.Getenv("OAUTH_STATE_SECRET"); secret != "" {
+			stateSecret = []byte(secret)
+			return
+		}
+		if secret := os.Getenv("JWT_SECRET"); secret != "" {
+			stateSecret = []byte(secret)
+			return
+		}
+		if !helper.EphemeralKeysAllowed() {
+			stateSecretErr = errors.New("OAUTH_STATE_SECRET or JWT_SECRET must be set (ALLOW_EPHEMERAL_KEYS=true for local development only)")
+			return
+		}
+
+		log.Println("ALLOW_EPHEMERAL_KEYS is set, using a random OAuth state secret")
+		stateSecret = make([]byte, 32)
+		_, stateSecretErr = rand.Read(stateSecret)
+	})
+	return stateSecretErr
+}
+
+func oauthStateSecret() []byte {
+	LoadOAuthStateSecret()
+	return stateSecret
+}
+
+func signOAuthPayload(payload string) string {
+	mac := hmac.New(sha256.New, oauthStateSecret())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SameSite cookie state. Flow yang dimulai lewat XHR dari frontend (beda site)
+// wajib None, karena browser membuang cookie Lax dari response cross-site.
+// Flow redirect (navigasi top-level) bisa diatur lewat OAUTH_COOKIE_SAMESITE (default Lax)
+func oauthCookieSameSite(crossSite bool) string {
+	if crossSite {
+		return fiber.CookieSameSiteNoneMode
+	}
+	switch strings.ToLower(os.Getenv("OAUTH_COOKIE_SAMESITE")) {
+	case "none":
+		return fiber.CookieSameSiteNoneMode
+	case "strict":
+		return fiber.CookieSameSiteStrictMode
+	default:
+		return fiber.CookieSameSiteLaxMode
+	}
+}
+
+// Secure juga kalau APP_URL https (TLS di-terminate proxy tanpa X-Forwarded-Proto)
+func oauthCookieSecure(c *fiber.Ctx, sameSite string) bool {
+	return sameSite == fiber.CookieSameSiteNoneMode ||
+		c.Protocol() == "https" ||
+		strings.HasPrefix(os.Getenv("APP_URL"), "https://")
+}
+
+// Buat state & PKCE verifier baru lalu simpan di cookie HttpOnly bertanda tangan.
+// crossSite = flow dimulai lewat XHR (response JSON), bukan redirect langsung
+func startOAuthFlow(c *fiber.Ctx, provider string, linkUserID uint, crossSite bool) (oauthFlow, error) {
+	state, err := helper.RandomToken(32)
+	if err != nil {
+		return oauthFlow{}, err
+	}
+
+	expires := time.Now().Add(oauthStateTTL)
+	flow := oauthFlow{
+		Provider:   provider,
+		State:      state,
+		Verifier:   oauth2.GenerateVerifier(),
+		Expires:    expires.Unix(),
+		LinkUserID: linkUserID,
+	}
+
+	raw, err := json.Marshal(flow)
+	if err != nil {
+		return oauthFlow{}, err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	sameSite := oauthCookieSameSite(crossSite)
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthStateCookie,
+		Value:    payload + "." + signOAuthPayload(payload),
+		Path:     oauthCookiePath,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   oauthCookieSecure(c, sameSite),
+		SameSite: sameSite,
+	})
+	return flow, nil
+}
+
+// Validasi state dari callback terhadap cookie, lalu hapus cookie-nya (sekali pakai)
+func finishOAuthFlow(c *fiber.Ctx, provider string) (oauthFlow, error) {
+	raw := c.Cookies(oauthStateCookie)
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthStateCookie,
+		Path:     oauthCookiePath,
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: true,
+	})
+
+	state := c.Query("state")
+	if raw == "" || state == "" {
+		return oauthFlow{}, ErrOAuthStateMissing
+	}
+
+	payload, signature, ok := strings.Cut(raw, ".")
+	if !ok || !hmac.Equal([]byte(signOAuthPayload(payload)), []byte(signature)) {
+		return oauthFlow{}, ErrOAuthStateMismatch
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return oauthFlow{}, ErrOAuthStateMismatch
+	}
+
+	var flow oauthFlow
+	if err := json.Unmarshal(decoded, &flow); err != nil {
+		return oauthFlow{}, ErrOAuthStateMismatch
+	}
+
+	if time.Now().Unix() > flow.Expires {
+		return oauthFlow{}, ErrOAuthStateExpired
+	}
+
+	if flow.Provider != provider || subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return oauthFlow{}, ErrOAuthStateMismatch
+	}
+	return flow, nil
+}
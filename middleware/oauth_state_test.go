@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOAuthStateCookieAttributes(t *testing.T) {
+	t.Setenv("OAUTH_STATE_SECRET", "test-secret")
+	t.Setenv("OAUTH_COOKIE_SAMESITE", "")
+
+	tests := []struct {
+		name       string
+		crossSite  bool
+		appURL     string
+		wantSite   http.SameSite
+		wantSecure bool
+	}{
+		{"XHR flow is SameSite=None and Secure", true, "", http.SameSiteNoneMode, true},
+		{"redirect flow over http stays Lax", false, "", http.SameSiteLaxMode, false},
+		{"redirect flow behind TLS proxy is Secure", false, "https://api.example.com", http.SameSiteLaxMode, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_URL", tt.appURL)
+
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				_, err := startOAuthFlow(c, "google", 0, tt.crossSite)
+				return err
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var cookie *http.Cookie
+			for _, c := range resp.Cookies() {
+				if c.Name == oauthStateCookie {
+					cookie = c
+				}
+			}
+			if cookie == nil {
+				t.Fatalf("no %s cookie in %v", oauthStateCookie, resp.Header.Values("Set-Cookie"))
+			}
+			if cookie.SameSite != tt.wantSite {
+				t.Errorf("SameSite = %v, want %v (%s)", cookie.SameSite, tt.wantSite, strings.Join(resp.Header.Values("Set-Cookie"), "; "))
+			}
+			if cookie.Secure != tt.wantSecure {
+				t.Errorf("Secure = %v, want %v", cookie.Secure, tt.wantSecure)
+			}
+			if !cookie.HttpOnly {
+				t.Error("state cookie is not HttpOnly")
+			}
+		})
+	}
+}
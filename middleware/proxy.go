@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"log"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Header internal berisi IP client hasil resolve X-Forwarded-For, dipakai
+// sebagai ProxyHeader Fiber supaya c.IP() mengembalikan IP client asli
+const ClientIPHeader = "X-Resolved-Client-IP"
+
+// Default: jaringan private & loopback (load balancer Render / Koyeb ada di jaringan internal)
+var defaultTrustedProxies = []string{
+	"127.0.0.0/8", "::1/128",
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+	"100.64.0.0/10", "fc00::/7",
+}
+
+// TrustedProxies membaca TRUSTED_PROXIES (IP / CIDR dipisah koma).
+// Entry yang tidak valid diabaikan dengan log.
+func TrustedProxies() []string {
+	value := strings.TrimSpace(os.Getenv("TRUSTED_PROXIES"))
+	if value == "" {
+		return defaultTrustedProxies
+	}
+
+	var proxies []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, err := parseProxyPrefix(entry); err != nil {
+			log.Printf("Ignoring invalid TRUSTED_PROXIES entry %q: %v", entry, err)
+			continue
+		}
+		proxies = append(proxies, entry)
+	}
+	return proxies
+}
+
+func parseProxyPrefix(entry string) (netip.Prefix, error) {
+	if strings.Contains(entry, "/") {
+		return netip.ParsePrefix(entry)
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ResolveClientIP mengisi ClientIPHeader dari X-Forwarded-For, hanya kalau
+// request datang dari proxy terpercaya. Rantai dibaca dari kanan dan hop proxy
+// terpercaya dilewati, jadi IP palsu yang dikirim client di kiri tidak dipakai.
+// Harus dipasang paling awal, sebelum middleware lain memanggil c.IP().
+func ResolveClientIP(trusted []string) fiber.Handler {
+	var prefixes []netip.Prefix
+	for _, entry := range trusted {
+		if prefix, err := parseProxyPrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		}
+	}
+
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(c *fiber.Ctx) error {
+		// Header dari client selalu dibuang
+		c.Request().Header.Del(ClientIPHeader)
+
+		remote, ok := netip.AddrFromSlice(c.Context().RemoteIP())
+		if !ok || !isTrusted(remote) {
+			return c.Next()
+		}
+
+		client := remote.Unmap()
+		hops := strings.Split(c.Get(fiber.HeaderXForwardedFor), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			client = addr.Unmap()
+			if !isTrusted(client) {
+				break
+			}
+		}
+
+		c.Request().Header.Set(ClientIPHeader, client.String())
+		return c.Next()
+	}
+}
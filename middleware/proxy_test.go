@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestResolveClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []string
+		xff     string
+		spoof   string
+		want    string
+	}{
+		{"untrusted peer ignores headers", []string{"10.0.0.0/8"}, "203.0.113.7", "198.51.100.1", "0.0.0.0"},
+		{"trusted peer uses forwarded client", []string{"0.0.0.0", "10.0.0.0/8"}, "203.0.113.7", "", "203.0.113.7"},
+		{"spoofed left entries are skipped", []string{"0.0.0.0", "10.0.0.0/8"}, "198.51.100.1, 203.0.113.7, 10.1.2.3", "198.51.100.1", "203.0.113.7"},
+		{"invalid hop stops the walk", []string{"0.0.0.0", "10.0.0.0/8"}, "203.0.113.7, garbage, 10.1.2.3", "", "10.1.2.3"},
+		{"no header falls back to peer", []string{"0.0.0.0"}, "", "", "0.0.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{
+				ProxyHeader:             ClientIPHeader,
+				EnableTrustedProxyCheck: true,
+				TrustedProxies:          tt.trusted,
+			})
+			app.Use(ResolveClientIP(tt.trusted))
+			app.Get("/", func(c *fiber.Ctx) error { return c.SendString(c.IP()) })
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.xff != "" {
+				req.Header.Set(fiber.HeaderXForwardedFor, tt.xff)
+			}
+			if tt.spoof != "" {
+				req.Header.Set(ClientIPHeader, tt.spoof)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.want {
+				t.Errorf("c.IP() = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
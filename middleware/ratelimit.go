@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/MashuNakamura/todolist-backend/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Key limiter berdasarkan IP
+func ByIP(c *fiber.Ctx) string {
+	return c.IP()
+}
+
+// Key limiter berdasarkan field "email" di body request. Di-parse dengan
+// BodyParser seperti di controller, supaya body form-urlencoded juga kena limit
+func ByEmail(c *fiber.Ctx) string {
+	var input models.ForgotPassword
+	if err := c.BodyParser(&input); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(input.Email))
+}
+
+// Response 429 dengan header Retry-After
+func TooManyRequests(c *fiber.Ctx, retryAfter time.Duration, message string) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return c.Status(429).JSON(models.Ret{
+		Success: false,
+		Message: message,
+		Error:   429,
+	})
+}
+
+// Batasi max request per sliding window untuk setiap key dari keyFunc.
+// Request tanpa key (misal body tanpa email) tidak dibatasi
+func RateLimit(name string, max int, window time.Duration, keyFunc func(*fiber.Ctx) string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := keyFunc(c)
+		if key == "" {
+			return c.Next()
+		}
+
+		allowed, retryAfter, err := ratelimit.Allow(name+":"+key, max, window)
+		if err != nil {
+			// Store bermasalah: jangan blokir semua user
+			return c.Next()
+		}
+		if !allowed {
+			return TooManyRequests(c, retryAfter, "Too many requests, please try again later")
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Hanya session login dengan salah satu role yang diminta yang boleh lewat.
+// Role dibaca dari database supaya penurunan role langsung berlaku
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(uint)
+		if !ok {
+			return c.Status(403).JSON(models.Ret{
+				Success: false,
+				Message: "Forbidden",
+				Error:   403,
+			})
+		}
+
+		var user models.User
+		if err := config.DB.Select("id", "role", "disabled_at").First(&user, userID).Error; err != nil {
+			return c.Status(401).JSON(models.Ret{
+				Success: false,
+				Message: "Unauthorized: Invalid User Session",
+				Error:   401,
+			})
+		}
+
+		if user.DisabledAt != nil || !slices.Contains(roles, user.Role) {
+			return c.Status(403).JSON(models.Ret{
+				Success: false,
+				Message: "Forbidden: insufficient role",
+				Error:   403,
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/MashuNakamura/todolist-backend/config"
+	"github.com/MashuNakamura/todolist-backend/helper"
+	"github.com/MashuNakamura/todolist-backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Batasi akun yang belum verifikasi email sesuai EMAIL_VERIFICATION_MODE
+func VerifiedEmail(c *fiber.Ctx) error {
+	mode := helper.EmailVerificationMode()
+	if mode == helper.EmailVerificationOff {
+		return c.Next()
+	}
+
+	// Mode readonly: request baca tetap boleh
+	if mode == helper.EmailVerificationReadOnly && (c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead) {
+		return c.Next()
+	}
+
+	userID, _ := AuthUserID(c)
+
+	var user models.User
+	if err := config.DB.Select("id, email_verified").First(&user, userID).Error; err != nil || !user.EmailVerified {
+		return c.Status(403).JSON(models.Ret{
+			Success: false,
+			Message: "Please verify your email to use this feature",
+			Error:   403,
+		})
+	}
+
+	return c.Next()
+}
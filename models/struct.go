@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/lib/pq"
@@ -13,6 +16,7 @@ type Ret struct {
 	Message string `json:"message"`
 	Error   int    `json:"code"`
 	Data    any    `json:"data"`
+	Meta    any    `json:"meta,omitempty"`
 }
 
 // 1. Tabel Users
@@ -23,8 +27,172 @@ type User struct {
 	Password   string     `json:"-"`
 	OTP        string     `json:"-"`
 	OTPExpiry  int64      `json:"-"`
-	Tasks      []Task     `json:"tasks"`
-	Categories []Category `json:"categories"`
+	Tasks      []Task     `json:"tasks" gorm:"constraint:OnDelete:CASCADE"`
+	Categories []Category `json:"categories" gorm:"constraint:OnDelete:CASCADE"`
+
+	// Pembatasan OTP reset password (OTP disimpan dalam bentuk hash)
+	OTPAttempts int   `json:"-"`
+	OTPSentAt   int64 `json:"-"`
+
+	// Akun lama (sebelum ada verifikasi email) di-backfill jadi verified saat migrasi
+	EmailVerified bool `json:"email_verified" gorm:"not null;default:false"`
+
+	// Two-Factor Authentication (TOTP)
+	TOTPSecret   string `json:"-"`
+	TOTPEnabled  bool   `json:"totp_enabled"`
+	TOTPLastStep int64  `json:"-"`
+
+	// Role & moderasi akun oleh admin
+	Role                  string     `json:"role" gorm:"not null;default:user"`
+	DisabledAt            *time.Time `json:"disabled_at"`
+	PasswordResetRequired bool       `json:"password_reset_required" gorm:"not null;default:false"`
+
+	// Hapus akun: user dihapus permanen setelah tanggal ini (null = tidak dijadwalkan)
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+
+	// Preferensi user (time zone, locale, default task)
+	Preference *UserPreference `json:"preferences,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+
+	// Foto profil: prefix object di storage, URL dihitung di AfterFind
+	AvatarKey        string            `json:"-"`
+	AvatarURL        string            `json:"avatar_url,omitempty" gorm:"-"`
+	AvatarThumbnails map[string]string `json:"avatar_thumbnails,omitempty" gorm:"-"`
+}
+
+// Ukuran thumbnail avatar (px), yang pertama dipakai sebagai avatar_url
+var AvatarSizes = []int{256, 128, 64}
+
+// Key object avatar di storage untuk satu ukuran
+func AvatarObjectKey(prefix string, size int) string {
+	return fmt.Sprintf("%s/%d.jpg", prefix, size)
+}
+
+// Isi URL avatar (APP_URL opsional, tanpa itu URL relatif ke backend)
+func (u *User) AfterFind(tx *gorm.DB) error {
+	if u.AvatarKey == "" {
+		return nil
+	}
+
+	u.AvatarThumbnails = map[string]string{}
+	for _, size := range AvatarSizes {
+		u.AvatarThumbnails[strconv.Itoa(size)] = os.Getenv("APP_URL") + "/api/" + AvatarObjectKey(u.AvatarKey, size)
+	}
+	u.AvatarURL = u.AvatarThumbnails[strconv.Itoa(AvatarSizes[0])]
+	return nil
+}
+
+// 1a. Tabel Refresh Tokens (disimpan dalam bentuk hash, satu family per login)
+type RefreshToken struct {
+	gorm.Model
+	UserID       uint       `json:"user_id" gorm:"index"`
+	TokenHash    string     `json:"-" gorm:"uniqueIndex"`
+	FamilyID     string     `json:"family_id" gorm:"index"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uint      `json:"replaced_by_id"`
+}
+
+// 1b. Tabel Sessions (Device yang login, satu per refresh token family)
+type Session struct {
+	gorm.Model
+	UserID     uint       `json:"-" gorm:"index"`
+	FamilyID   string     `json:"-" gorm:"uniqueIndex"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current" gorm:"-"`
+}
+
+// 1c. Tabel Recovery Codes 2FA (sekali pakai, disimpan dalam bentuk hash)
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `json:"-" gorm:"index"`
+	CodeHash string     `json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// 1d. Tabel Two-Factor Challenge (Login yang menunggu kode 2FA)
+type TwoFactorChallenge struct {
+	gorm.Model
+	UserID    uint      `json:"-" gorm:"index"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Attempts  int       `json:"-"`
+}
+
+// 1e. Tabel Email Verification (satu per user, link token & kode disimpan dalam bentuk hash)
+type EmailVerification struct {
+	gorm.Model
+	UserID    uint      `json:"-" gorm:"uniqueIndex"`
+	TokenHash string    `json:"-" gorm:"index"`
+	CodeHash  string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Attempts  int       `json:"-"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// 1f. Tabel Identities (Akun social login yang di-link ke user)
+type Identity struct {
+	gorm.Model
+	UserID   uint   `json:"-" gorm:"index"`
+	Provider string `json:"provider" gorm:"uniqueIndex:idx_identity_subject"`
+	Subject  string `json:"-" gorm:"uniqueIndex:idx_identity_subject"`
+	Email    string `json:"email"`
+}
+
+// 1g. Tabel API Tokens (Personal Access Token untuk script & integrasi)
+type APIToken struct {
+	gorm.Model
+	UserID     uint           `json:"-" gorm:"index"`
+	Name       string         `json:"name"`
+	Prefix     string         `json:"prefix"`
+	TokenHash  string         `json:"-" gorm:"uniqueIndex"`
+	Scopes     pq.StringArray `json:"scopes" gorm:"type:text[]"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	RevokedAt  *time.Time     `json:"revoked_at"`
+}
+
+// 1h. Tabel Account Deletion Codes (OTP konfirmasi hapus akun, disimpan dalam bentuk hash)
+type AccountDeletionCode struct {
+	gorm.Model
+	UserID    uint      `json:"-" gorm:"uniqueIndex"`
+	CodeHash  string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Attempts  int       `json:"-"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// 1i. Tabel Email Changes (Perubahan email yang menunggu konfirmasi, kode disimpan dalam bentuk hash)
+type EmailChange struct {
+	gorm.Model
+	UserID    uint      `json:"-" gorm:"uniqueIndex"`
+	NewEmail  string    `json:"new_email"`
+	CodeHash  string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Attempts  int       `json:"-"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// 1j. Tabel User Preferences (satu baris per user, dibuat saat pertama kali diubah)
+type UserPreference struct {
+	gorm.Model
+	UserID          uint   `json:"-" gorm:"uniqueIndex"`
+	TimeZone        string `json:"time_zone"`
+	Locale          string `json:"locale"`
+	WeekStart       string `json:"week_start"`
+	DefaultPriority string `json:"default_priority"`
+	DefaultStatus   string `json:"default_status"`
+}
+
+// 1k. Tabel OAuth Login Codes (kode sekali pakai untuk ambil token setelah login OAuth, disimpan dalam bentuk hash)
+type OAuthLoginCode struct {
+	ID        uint      `json:"-" gorm:"primarykey"`
+	UserID    uint      `json:"-" gorm:"index"`
+	CodeHash  string    `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time `json:"-" gorm:"index"`
+	CreatedAt time.Time `json:"-"`
 }
 
 // 2. Tabel Tasks (Todolist)
@@ -39,6 +207,93 @@ type Task struct {
 	DueDate   *time.Time     `json:"due_date"`
 	Tags      pq.StringArray `json:"tags" gorm:"type:text[]"`
 	UserID    uint           `json:"user_id"`
+	Subtasks  []Subtask      `json:"subtasks,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Progress  int            `json:"progress" gorm:"-"`
+
+	// Recurring Task (RRULE, lihat package recurrence)
+	Recurrence string `json:"recurrence"`
+	Occurrence int    `json:"occurrence" gorm:"default:1"`
+	NextTaskID *uint  `json:"next_task_id"`
+
+	// Reminder dalam menit sebelum due date (null = default, [] = nonaktif)
+	ReminderOffsets pq.Int64Array `json:"reminder_offsets" gorm:"type:bigint[]"`
+
+	// Jumlah komentar (tidak termasuk yang sudah dihapus), diisi di controller
+	CommentCount int64 `json:"comment_count" gorm:"-"`
+}
+
+// Hitung progress (%) dari subtasks yang sudah di preload
+func (t *Task) AfterFind(tx *gorm.DB) error {
+	if len(t.Subtasks) == 0 {
+		return nil
+	}
+
+	done := 0
+	for _, subtask := range t.Subtasks {
+		if subtask.Done {
+			done++
+		}
+	}
+	t.Progress = done * 100 / len(t.Subtasks)
+	return nil
+}
+
+// 2a. Tabel Subtasks (Checklist Item dari Task)
+type Subtask struct {
+	gorm.Model
+	TaskID   uint   `json:"task_id" gorm:"index"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Position int    `json:"position"`
+}
+
+// 2b. Tabel Task Reminders (Log reminder yang sudah terkirim, untuk dedup)
+type TaskReminder struct {
+	ID      uint      `json:"id" gorm:"primarykey"`
+	TaskID  uint      `json:"task_id" gorm:"uniqueIndex:idx_task_reminder"`
+	Offset  int64     `json:"offset" gorm:"uniqueIndex:idx_task_reminder"`
+	DueDate time.Time `json:"due_date" gorm:"uniqueIndex:idx_task_reminder"`
+	SentAt  time.Time `json:"sent_at"`
+	Task    Task      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// 2c. Tabel Task Attachments (Metadata file, isi file ada di storage)
+type TaskAttachment struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	TaskID      uint      `json:"task_id" gorm:"index"`
+	UserID      uint      `json:"-" gorm:"index"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	Task        Task      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// 2d. Tabel Task Comments (soft delete, edited_at diisi saat body diubah)
+type TaskComment struct {
+	gorm.Model
+	TaskID   uint           `json:"task_id" gorm:"index"`
+	UserID   uint           `json:"user_id" gorm:"index"`
+	Body     string         `json:"body"`
+	EditedAt *time.Time     `json:"edited_at"`
+	Author   *CommentAuthor `json:"author,omitempty" gorm:"-"`
+	Task     Task           `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// 2e. Tabel Task Activities (Riwayat perubahan task & subtask, satu baris per field yang berubah)
+// Tanpa foreign key ke tasks: riwayat tetap ada walau task dihapus permanen
+type TaskActivity struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	TaskID    uint      `json:"task_id" gorm:"index"`
+	SubtaskID *uint     `json:"subtask_id,omitempty"`
+	UserID    uint      `json:"user_id" gorm:"index:idx_task_activity_user_created,priority:1"`
+	Action    string    `json:"action"`
+	Field     string    `json:"field,omitempty"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_task_activity_user_created,priority:2"`
+	TaskTitle string    `json:"task_title,omitempty" gorm:"-"`
 }
 
 // 3. Tabel Categories (Label Warna)
@@ -64,6 +319,12 @@ type UpdateTask struct {
 	Time      string   `json:"time"`
 	DueDate   string   `json:"due_date"`
 	Tags      []string `json:"tags"`
+
+	// nil = tidak diubah, "" = hapus recurrence
+	Recurrence *string `json:"recurrence"`
+
+	// nil = tidak diubah, [] = nonaktifkan reminder
+	ReminderOffsets []int64 `json:"reminder_offsets"`
 }
 
 // 6. Struct untuk Update Batch Status
@@ -89,3 +350,189 @@ type ChangePassword struct {
 	OldPassword string `json:"old_password"`
 	NewPassword string `json:"new_password"`
 }
+
+// 10. Struct untuk Filter, Sort & Pagination Task
+type TaskQuery struct {
+	Status    string `query:"status"`
+	Priority  string `query:"priority"`
+	Tag       string `query:"tag"`
+	DueBefore string `query:"due_before"`
+	DueAfter  string `query:"due_after"`
+	Overdue   bool   `query:"overdue"`
+	Today     bool   `query:"today"`
+	ThisWeek  bool   `query:"this_week"`
+	Sort      string `query:"sort"`
+	Order     string `query:"order"`
+	Page      int    `query:"page"`
+	Limit     int    `query:"limit"`
+}
+
+// 11. Struct untuk Pagination Meta
+type Pagination struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// 12. Struct untuk Tombstone (record yang sudah di soft-delete)
+type Tombstone struct {
+	ID        uint      `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// 13. Struct untuk Response Sync
+type SyncResponse struct {
+	Tasks             []Task      `json:"tasks"`
+	Categories        []Category  `json:"categories"`
+	DeletedTasks      []Tombstone `json:"deleted_tasks"`
+	DeletedCategories []Tombstone `json:"deleted_categories"`
+	Cursor            string      `json:"cursor"`
+}
+
+// 14. Struct untuk Restore / Purge Trash
+type TrashIDs struct {
+	IDs []uint `json:"ids"`
+}
+
+// 15. Struct untuk Update Subtask
+type UpdateSubtask struct {
+	Title    string `json:"title"`
+	Done     *bool  `json:"done"`
+	Position *int   `json:"position"`
+}
+
+// 16. Struct untuk Token Pair (Access + Refresh)
+type TokenPair struct {
+	AccessToken      string `json:"token"`
+	ExpiresAt        int64  `json:"expires"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresAt int64  `json:"refresh_expires"`
+}
+
+// 17. Struct untuk Refresh Token Request
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// 18. Struct untuk Kode 2FA
+type TwoFactorCode struct {
+	Code string `json:"code"`
+}
+
+// 19. Struct untuk Login 2FA (Challenge)
+type TwoFactorLogin struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// 20. Struct untuk Disable 2FA
+type DisableTwoFactor struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// 21. Struct untuk Verify Email (pakai token dari link, atau email + kode)
+type VerifyEmail struct {
+	Token string `json:"token"`
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// 22. Struct untuk Set Password Pertama (akun social login)
+type SetPassword struct {
+	NewPassword string `json:"new_password"`
+}
+
+// 23. Struct untuk Create API Token
+type CreateAPIToken struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// 24. Struct untuk Search & Pagination User (Admin)
+type AdminUserQuery struct {
+	Search string `query:"q"`
+	Role   string `query:"role"`
+	Status string `query:"status"`
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+}
+
+// 25. Struct untuk Response User (Admin) beserta jumlah task
+type AdminUser struct {
+	ID                    uint       `json:"id"`
+	Name                  string     `json:"name"`
+	Email                 string     `json:"email"`
+	Role                  string     `json:"role"`
+	EmailVerified         bool       `json:"email_verified"`
+	TOTPEnabled           bool       `json:"totp_enabled"`
+	DisabledAt            *time.Time `json:"disabled_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+	TaskCount             int64      `json:"task_count"`
+	OpenTaskCount         int64      `json:"open_task_count"`
+	DoneTaskCount         int64      `json:"done_task_count"`
+}
+
+// 26. Struct untuk Update Role User (Admin)
+type UpdateUserRole struct {
+	Role string `json:"role"`
+}
+
+// 27. Struct untuk Konfirmasi Hapus Akun (password, atau OTP untuk akun social login)
+type DeleteAccount struct {
+	Password string `json:"password"`
+	OTP      string `json:"otp"`
+}
+
+// 28. Struct untuk Request Ganti Email
+type ChangeEmail struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
+// 29. Struct untuk Konfirmasi Ganti Email
+type ConfirmEmailChange struct {
+	Code string `json:"code"`
+}
+
+// 30. Struct untuk Update Preferences (field kosong tidak diubah)
+type UpdatePreferences struct {
+	TimeZone        *string `json:"time_zone"`
+	Locale          *string `json:"locale"`
+	WeekStart       *string `json:"week_start"`
+	DefaultPriority *string `json:"default_priority"`
+	DefaultStatus   *string `json:"default_status"`
+}
+
+// 31. Struct untuk Create / Update Comment
+type UpdateComment struct {
+	Body string `json:"body"`
+}
+
+// 32. Struct untuk Author Comment (nama & avatar saja)
+type CommentAuthor struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+// 33. Struct untuk Pagination Activity (Timeline task & feed user)
+type ActivityQuery struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
+
+// 34. Struct untuk Tukar Kode Login OAuth dengan Token
+type OAuthCodeExchange struct {
+	Code string `json:"code"`
+}
+
+// 35. Struct untuk Create Task (due_date di-parse manual dengan time zone user)
+type CreateTask struct {
+	Task
+	DueDate string `json:"due_date"`
+}
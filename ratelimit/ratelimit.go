@@ -0,0 +1,155 @@
+// Package ratelimit berisi rate limit sliding window dan progressive lockout
+// login, di atas Store yang bisa diganti.
+package ratelimit
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Simpan timestamp hit terbaru per key. Default in-memory, isi DefaultStore dengan
+// store bersama (misal Redis sorted set) supaya limit berlaku di semua replica
+type Store interface {
+	// Catat hit kecuali sudah ada limit hit di dalam window (limit <= 0 = tanpa batas).
+	// Return hit di dalam window (terlama dulu) & apakah hit baru tercatat.
+	// Hit yang ditolak tidak disimpan, jadi flood tidak membuat key tumbuh melewati limit
+	Add(key string, now time.Time, window time.Duration, limit int) ([]time.Time, bool, error)
+	// Hit yang masih di dalam window, tanpa mencatat hit baru
+	Get(key string, now time.Time, window time.Duration) ([]time.Time, error)
+	// Hapus semua hit untuk key
+	Reset(key string) error
+}
+
+var DefaultStore Store = NewMemoryStore()
+
+// Store in-memory untuk satu proses
+type MemoryStore struct {
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{hits: map[string][]time.Time{}}
+}
+
+func (s *MemoryStore) prune(key string, now time.Time, window time.Duration) []time.Time {
+	hits := s.hits[key]
+	cutoff := now.Add(-window)
+
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	hits = hits[i:]
+
+	if len(hits) == 0 {
+		delete(s.hits, key)
+	} else {
+		s.hits[key] = hits
+	}
+	return hits
+}
+
+func (s *MemoryStore) Add(key string, now time.Time, window time.Duration, limit int) ([]time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hits := s.prune(key, now, window)
+	if limit > 0 && len(hits) >= limit {
+		return append([]time.Time(nil), hits...), false, nil
+	}
+
+	hits = append(hits, now)
+	s.hits[key] = hits
+	return append([]time.Time(nil), hits...), true, nil
+}
+
+func (s *MemoryStore) Get(key string, now time.Time, window time.Duration) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]time.Time(nil), s.prune(key, now, window)...), nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.hits, key)
+	return nil
+}
+
+// Buang key yang semua hit-nya sudah di luar window, dipanggil berkala supaya map tidak terus membesar
+func (s *MemoryStore) Cleanup(window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key := range s.hits {
+		s.prune(key, now, window)
+	}
+}
+
+// Catat hit untuk key dan cek masih dalam batas max per window.
+// Kalau tidak, durasi yang dikembalikan = waktu tunggu sampai request berikutnya boleh
+func Allow(key string, max int, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now()
+	hits, added, err := DefaultStore.Add(key, now, window, max)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if added {
+		return true, 0, nil
+	}
+	// Slot berikutnya terbuka saat hit tertua keluar dari window
+	return false, hits[0].Add(window).Sub(now), nil
+}
+
+// Progressive lockout untuk login yang gagal berulang kali
+const (
+	loginFailureWindow    = time.Hour
+	loginFailureThreshold = 5
+	loginLockoutBase      = time.Minute
+	loginLockoutMax       = time.Hour
+
+	// Lebih dari ini tidak menambah lama lock (sudah mentok di loginLockoutMax)
+	loginFailureMaxHits = loginFailureThreshold + 10
+)
+
+func loginKey(email string) string {
+	return "login-failure:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// Sisa waktu lock akun (0 = tidak di-lock). Setelah loginFailureThreshold kali gagal,
+// lama lock berlipat dua setiap gagal berikutnya
+func LoginLockout(email string) time.Duration {
+	now := time.Now()
+	hits, err := DefaultStore.Get(loginKey(email), now, loginFailureWindow)
+	if err != nil || len(hits) < loginFailureThreshold {
+		return 0
+	}
+
+	exponent := float64(len(hits) - loginFailureThreshold)
+	lock := time.Duration(float64(loginLockoutBase) * math.Pow(2, exponent))
+	if lock > loginLockoutMax {
+		lock = loginLockoutMax
+	}
+
+	if remaining := hits[len(hits)-1].Add(lock).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Catat login gagal untuk email
+func RecordLoginFailure(email string) {
+	DefaultStore.Add(loginKey(email), time.Now(), loginFailureWindow, loginFailureMaxHits)
+}
+
+// Hapus riwayat gagal setelah login berhasil
+func ResetLoginFailures(email string) {
+	DefaultStore.Reset(loginKey(email))
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreAddCapsAtLimit(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 100; i++ {
+		hits, added, err := store.Add("flood", now.Add(time.Duration(i)*100*time.Millisecond), time.Minute, 3)
+		if err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		if added != (i < 3) {
+			t.Errorf("hit %d: added = %v, want %v", i, added, i < 3)
+		}
+		if len(hits) > 3 {
+			t.Fatalf("hit %d: %d hits stored, want at most 3", i, len(hits))
+		}
+	}
+
+	// Hit yang ditolak tidak disimpan, jadi key kosong lagi setelah window lewat
+	hits, _ := store.Get("flood", now.Add(2*time.Minute), time.Minute)
+	if len(hits) != 0 {
+		t.Errorf("after window: %d hits, want 0", len(hits))
+	}
+
+	if _, added, _ := store.Add("flood", now.Add(2*time.Minute), time.Minute, 3); !added {
+		t.Error("hit after window was rejected")
+	}
+}
+
+func TestAllowRetryAfter(t *testing.T) {
+	DefaultStore = NewMemoryStore()
+	t.Cleanup(func() { DefaultStore = NewMemoryStore() })
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := Allow("key", 2, time.Minute); !ok {
+			t.Fatalf("request %d rejected", i)
+		}
+	}
+
+	ok, retryAfter, _ := Allow("key", 2, time.Minute)
+	if ok {
+		t.Fatal("request over limit allowed")
+	}
+	if retryAfter <= 0 || retryAfter > time.Minute {
+		t.Errorf("retryAfter = %s, want within (0, 1m]", retryAfter)
+	}
+}
@@ -0,0 +1,282 @@
+// Package recurrence mendukung sebagian kecil RRULE RFC 5545, dipakai untuk
+// generate occurrence berikutnya dari recurring task.
+//
+// Rule yang didukung:
+//
+//	FREQ=DAILY;INTERVAL=3                  setiap 3 hari
+//	FREQ=WEEKLY;BYDAY=MO,WE,FR             setiap Senin, Rabu & Jumat
+//	FREQ=MONTHLY;BYMONTHDAY=31             setiap tanggal 31 (atau hari terakhir bulan)
+//	FREQ=MONTHLY;BYMONTHDAY=-1             setiap hari terakhir bulan
+//	...;UNTIL=20261231T000000Z / ;COUNT=10 series yang ada batasnya
+//
+// Occurrence dihitung di kalender time zone occurrence sebelumnya, jadi jam
+// tetap sama walau melewati pergantian DST.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+const untilLayout = "20060102T150405Z"
+
+var (
+	ErrEmptyRule       = errors.New("recurrence: empty rule")
+	ErrInvalidFreq     = errors.New("recurrence: FREQ must be DAILY, WEEKLY or MONTHLY")
+	ErrInvalidInterval = errors.New("recurrence: INTERVAL must be a positive number")
+	ErrInvalidByDay    = errors.New("recurrence: BYDAY must be a list of MO, TU, WE, TH, FR, SA, SU")
+	ErrInvalidMonthDay = errors.New("recurrence: BYMONTHDAY must be between 1 and 31 or -1")
+	ErrInvalidUntil    = errors.New("recurrence: UNTIL must be formatted as 20060102T150405Z")
+	ErrInvalidCount    = errors.New("recurrence: COUNT must be a positive number")
+	ErrUntilAndCount   = errors.New("recurrence: UNTIL and COUNT cannot be combined")
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence rule hasil parse
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	Weekdays []time.Weekday // WEEKLY only
+	MonthDay int            // MONTHLY only, -1 means last day of month
+	Until    *time.Time
+	Count    int // total number of occurrences, including the first one
+}
+
+// Parse string RRULE seperti "FREQ=WEEKLY;BYDAY=MO,FR" (prefix "RRULE:" boleh ada)
+func Parse(value string) (Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return Rule{}, ErrEmptyRule
+	}
+
+	rule := Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("recurrence: invalid part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(val))
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return Rule{}, ErrInvalidInterval
+			}
+			rule.Interval = n
+		case "BYDAY":
+			weekdays, err := parseWeekdays(val)
+			if err != nil {
+				return Rule{}, err
+			}
+			rule.Weekdays = weekdays
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(val)
+			if err != nil || n == 0 || n < -1 || n > 31 {
+				return Rule{}, ErrInvalidMonthDay
+			}
+			rule.MonthDay = n
+		case "UNTIL":
+			until, err := time.Parse(untilLayout, strings.ToUpper(val))
+			if err != nil {
+				return Rule{}, ErrInvalidUntil
+			}
+			rule.Until = &until
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return Rule{}, ErrInvalidCount
+			}
+			rule.Count = n
+		default:
+			return Rule{}, fmt.Errorf("recurrence: unsupported part %q", key)
+		}
+	}
+
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+func parseWeekdays(value string) ([]time.Weekday, error) {
+	seen := map[time.Weekday]bool{}
+	var weekdays []time.Weekday
+	for _, code := range strings.Split(value, ",") {
+		weekday, ok := weekdayCodes[strings.ToUpper(strings.TrimSpace(code))]
+		if !ok {
+			return nil, ErrInvalidByDay
+		}
+		if !seen[weekday] {
+			seen[weekday] = true
+			weekdays = append(weekdays, weekday)
+		}
+	}
+	sortWeekdays(weekdays)
+	return weekdays, nil
+}
+
+// Minggu dimulai hari Senin (default RFC 5545 WKST=MO)
+func weekdayIndex(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+func sortWeekdays(weekdays []time.Weekday) {
+	sort.Slice(weekdays, func(i, j int) bool {
+		return weekdayIndex(weekdays[i]) < weekdayIndex(weekdays[j])
+	})
+}
+
+// Cek rule konsisten
+func (r Rule) Validate() error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly:
+	default:
+		return ErrInvalidFreq
+	}
+	if r.Interval <= 0 {
+		return ErrInvalidInterval
+	}
+	if len(r.Weekdays) > 0 && r.Freq != Weekly {
+		return fmt.Errorf("recurrence: BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if r.MonthDay != 0 && r.Freq != Monthly {
+		return fmt.Errorf("recurrence: BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	if r.MonthDay < -1 || r.MonthDay > 31 {
+		return ErrInvalidMonthDay
+	}
+	if r.Count < 0 {
+		return ErrInvalidCount
+	}
+	if r.Until != nil && r.Count > 0 {
+		return ErrUntilAndCount
+	}
+	return nil
+}
+
+// Lengkapi rule dari occurrence pertama: hari untuk WEEKLY tanpa BYDAY dan tanggal
+// untuk MONTHLY tanpa BYMONTHDAY, supaya series tanggal 31 tidak bergeser ke 28 setelah Februari
+func (r Rule) Anchor(start time.Time) Rule {
+	if r.Freq == Weekly && len(r.Weekdays) == 0 {
+		r.Weekdays = []time.Weekday{start.Weekday()}
+	}
+	if r.Freq == Monthly && r.MonthDay == 0 {
+		r.MonthDay = start.Day()
+	}
+	return r
+}
+
+// Format rule kembali ke sintaks RRULE
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.Weekdays) > 0 {
+		codes := make([]string, 0, len(r.Weekdays))
+		for _, weekday := range r.Weekdays {
+			codes = append(codes, strings.ToUpper(weekday.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.MonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.MonthDay))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrence setelah prev. occurrence = urutan prev di series (mulai 1) untuk COUNT.
+// Return false kalau series sudah selesai
+func (r Rule) Next(prev time.Time, occurrence int) (time.Time, bool) {
+	if r.Count > 0 && occurrence >= r.Count {
+		return time.Time{}, false
+	}
+
+	r = r.Anchor(prev)
+
+	var next time.Time
+	switch r.Freq {
+	case Daily:
+		next = addDays(prev, r.Interval)
+	case Weekly:
+		next = r.nextWeekly(prev)
+	case Monthly:
+		next = r.nextMonthly(prev)
+	default:
+		return time.Time{}, false
+	}
+
+	if r.Until != nil && next.After(*r.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (r Rule) nextWeekly(prev time.Time) time.Time {
+	current := weekdayIndex(prev.Weekday())
+
+	// Sisa hari di minggu yang sama
+	for _, weekday := range r.Weekdays {
+		if idx := weekdayIndex(weekday); idx > current {
+			return addDays(prev, idx-current)
+		}
+	}
+
+	// Hari pertama yang dipilih, INTERVAL minggu kemudian
+	weekStart := addDays(prev, -current)
+	return addDays(weekStart, 7*r.Interval+weekdayIndex(r.Weekdays[0]))
+}
+
+func (r Rule) nextMonthly(prev time.Time) time.Time {
+	year, month, _ := prev.Date()
+	first := time.Date(year, month+time.Month(r.Interval), 1, 0, 0, 0, 0, prev.Location())
+
+	day := r.MonthDay
+	last := daysIn(first.Year(), first.Month(), prev.Location())
+	if day == -1 || day > last {
+		day = last
+	}
+
+	hour, minute, sec := prev.Clock()
+	return time.Date(first.Year(), first.Month(), day, hour, minute, sec, prev.Nanosecond(), prev.Location())
+}
+
+// Geser per hari kalender, jam tetap sama walau ada pergantian DST di antaranya
+func addDays(t time.Time, days int) time.Time {
+	year, month, day := t.Date()
+	hour, minute, sec := t.Clock()
+	return time.Date(year, month, day+days, hour, minute, sec, t.Nanosecond(), t.Location())
+}
+
+func daysIn(year int, month time.Month, loc *time.Location) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+}
@@ -0,0 +1,418 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, minute int, loc *time.Location) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, loc)
+}
+
+// series returns up to n occurrences following start (start not included).
+func series(t *testing.T, rule Rule, start time.Time, n int) []time.Time {
+	t.Helper()
+
+	var out []time.Time
+	prev := start
+	for occurrence := 1; len(out) < n; occurrence++ {
+		next, ok := rule.Next(prev, occurrence)
+		if !ok {
+			break
+		}
+		if !next.After(prev) {
+			t.Fatalf("Next(%s) = %s, not after previous occurrence", prev, next)
+		}
+		out = append(out, next)
+		prev = next
+	}
+	return out
+}
+
+func assertSeries(t *testing.T, got, want []time.Time) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d occurrences %v, want %d %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrence %d = %s, want %s", i+2, got[i], want[i])
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	until := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  Rule
+	}{
+		{"FREQ=DAILY", Rule{Freq: Daily, Interval: 1}},
+		{"RRULE:FREQ=DAILY;INTERVAL=3", Rule{Freq: Daily, Interval: 3}},
+		{"freq=weekly;byday=fr,mo,we,mo", Rule{Freq: Weekly, Interval: 1, Weekdays: []time.Weekday{time.Monday, time.Wednesday, time.Friday}}},
+		{"FREQ=WEEKLY;BYDAY=SU,MO", Rule{Freq: Weekly, Interval: 1, Weekdays: []time.Weekday{time.Monday, time.Sunday}}},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", Rule{Freq: Monthly, Interval: 1, MonthDay: 31}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", Rule{Freq: Monthly, Interval: 1, MonthDay: -1}},
+		{"FREQ=DAILY;UNTIL=20261231T000000Z", Rule{Freq: Daily, Interval: 1, Until: &until}},
+		{"FREQ=DAILY;COUNT=10", Rule{Freq: Daily, Interval: 1, Count: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.value, err)
+			}
+			if got.String() != tt.want.String() {
+				t.Errorf("Parse(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+			if got.Freq != tt.want.Freq || got.Interval != tt.want.Interval || got.MonthDay != tt.want.MonthDay || got.Count != tt.want.Count {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		value string
+		want  error
+	}{
+		{"", ErrEmptyRule},
+		{"FREQ=YEARLY", ErrInvalidFreq},
+		{"FREQ=DAILY;INTERVAL=0", ErrInvalidInterval},
+		{"FREQ=WEEKLY;BYDAY=XX", ErrInvalidByDay},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", ErrInvalidMonthDay},
+		{"FREQ=MONTHLY;BYMONTHDAY=-2", ErrInvalidMonthDay},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", ErrInvalidMonthDay},
+		{"FREQ=DAILY;UNTIL=2026-12-31", ErrInvalidUntil},
+		{"FREQ=DAILY;COUNT=-1", ErrInvalidCount},
+		{"FREQ=DAILY;COUNT=3;UNTIL=20261231T000000Z", ErrUntilAndCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if _, err := Parse(tt.value); !errors.Is(err, tt.want) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.value, err, tt.want)
+			}
+		})
+	}
+
+	for _, value := range []string{"FREQ=DAILY;BYDAY=MO", "FREQ=WEEKLY;BYMONTHDAY=1", "FREQ=DAILY;BYSETPOS=1", "FREQ"} {
+		if _, err := Parse(value); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	start := date(2026, time.January, 31, 9, 0, time.UTC) // Saturday
+
+	weekly := Rule{Freq: Weekly, Interval: 1}.Anchor(start)
+	if len(weekly.Weekdays) != 1 || weekly.Weekdays[0] != time.Saturday {
+		t.Errorf("weekly Anchor weekdays = %v, want [Saturday]", weekly.Weekdays)
+	}
+
+	monthly := Rule{Freq: Monthly, Interval: 1}.Anchor(start)
+	if monthly.MonthDay != 31 {
+		t.Errorf("monthly Anchor month day = %d, want 31", monthly.MonthDay)
+	}
+
+	// Bagian yang sudah diisi tidak ditimpa
+	explicit := Rule{Freq: Monthly, Interval: 1, MonthDay: -1}.Anchor(start)
+	if explicit.MonthDay != -1 {
+		t.Errorf("explicit Anchor month day = %d, want -1", explicit.MonthDay)
+	}
+
+	// Tanpa anchor, seri tanggal 31 akan bergeser ke 28 setelah Februari
+	rule, _ := Parse("FREQ=MONTHLY")
+	rule = rule.Anchor(start)
+	assertSeries(t, series(t, rule, start, 3), []time.Time{
+		date(2026, time.February, 28, 9, 0, time.UTC),
+		date(2026, time.March, 31, 9, 0, time.UTC),
+		date(2026, time.April, 30, 9, 0, time.UTC),
+	})
+}
+
+func TestNextMonthEnd(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "BYMONTHDAY=31 leap year",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: date(2024, time.January, 31, 8, 30, time.UTC),
+			want: []time.Time{
+				date(2024, time.February, 29, 8, 30, time.UTC),
+				date(2024, time.March, 31, 8, 30, time.UTC),
+				date(2024, time.April, 30, 8, 30, time.UTC),
+				date(2024, time.May, 31, 8, 30, time.UTC),
+			},
+		},
+		{
+			name:  "BYMONTHDAY=31 non-leap year",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: date(2025, time.January, 31, 8, 30, time.UTC),
+			want: []time.Time{
+				date(2025, time.February, 28, 8, 30, time.UTC),
+				date(2025, time.March, 31, 8, 30, time.UTC),
+				date(2025, time.April, 30, 8, 30, time.UTC),
+			},
+		},
+		{
+			name:  "BYMONTHDAY=-1 leap year",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: date(2024, time.January, 31, 17, 0, time.UTC),
+			want: []time.Time{
+				date(2024, time.February, 29, 17, 0, time.UTC),
+				date(2024, time.March, 31, 17, 0, time.UTC),
+				date(2024, time.April, 30, 17, 0, time.UTC),
+			},
+		},
+		{
+			name:  "BYMONTHDAY=-1 non-leap year",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: date(2025, time.January, 31, 17, 0, time.UTC),
+			want: []time.Time{
+				date(2025, time.February, 28, 17, 0, time.UTC),
+				date(2025, time.March, 31, 17, 0, time.UTC),
+				date(2025, time.April, 30, 17, 0, time.UTC),
+			},
+		},
+		{
+			name:  "BYMONTHDAY=30 skips past February",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=30",
+			start: date(2025, time.January, 30, 12, 0, time.UTC),
+			want: []time.Time{
+				date(2025, time.February, 28, 12, 0, time.UTC),
+				date(2025, time.March, 30, 12, 0, time.UTC),
+			},
+		},
+		{
+			name:  "INTERVAL=12 from leap day",
+			rule:  "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=29",
+			start: date(2024, time.February, 29, 9, 0, time.UTC),
+			want: []time.Time{
+				date(2025, time.February, 28, 9, 0, time.UTC),
+				date(2026, time.February, 28, 9, 0, time.UTC),
+				date(2027, time.February, 28, 9, 0, time.UTC),
+				date(2028, time.February, 29, 9, 0, time.UTC),
+			},
+		},
+		{
+			name:  "December rolls over to next year",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: date(2025, time.December, 31, 9, 0, time.UTC),
+			want: []time.Time{
+				date(2026, time.January, 31, 9, 0, time.UTC),
+				date(2026, time.February, 28, 9, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.rule, err)
+			}
+			assertSeries(t, series(t, rule.Anchor(tt.start), tt.start, len(tt.want)), tt.want)
+		})
+	}
+}
+
+func TestNextWeekly(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "INTERVAL=2 MO,WE,FR",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE,FR",
+			start: date(2026, time.January, 5, 9, 0, time.UTC), // Monday
+			want: []time.Time{
+				date(2026, time.January, 7, 9, 0, time.UTC),
+				date(2026, time.January, 9, 9, 0, time.UTC),
+				date(2026, time.January, 19, 9, 0, time.UTC),
+				date(2026, time.January, 21, 9, 0, time.UTC),
+				date(2026, time.January, 23, 9, 0, time.UTC),
+				date(2026, time.February, 2, 9, 0, time.UTC),
+			},
+		},
+		{
+			name:  "INTERVAL=3 TU,TH starting on last day of the week",
+			rule:  "FREQ=WEEKLY;INTERVAL=3;BYDAY=TH,TU",
+			start: date(2026, time.January, 8, 18, 0, time.UTC), // Thursday
+			want: []time.Time{
+				date(2026, time.January, 27, 18, 0, time.UTC),
+				date(2026, time.January, 29, 18, 0, time.UTC),
+				date(2026, time.February, 17, 18, 0, time.UTC),
+			},
+		},
+		{
+			name:  "INTERVAL=2 SA,SU keeps Sunday in the same week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,SA",
+			start: date(2026, time.January, 10, 10, 0, time.UTC), // Saturday
+			want: []time.Time{
+				date(2026, time.January, 11, 10, 0, time.UTC),
+				date(2026, time.January, 24, 10, 0, time.UTC),
+				date(2026, time.January, 25, 10, 0, time.UTC),
+			},
+		},
+		{
+			name:  "without BYDAY anchors on start weekday",
+			rule:  "FREQ=WEEKLY;INTERVAL=2",
+			start: date(2026, time.January, 7, 9, 0, time.UTC), // Wednesday
+			want: []time.Time{
+				date(2026, time.January, 21, 9, 0, time.UTC),
+				date(2026, time.February, 4, 9, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.rule, err)
+			}
+			assertSeries(t, series(t, rule.Anchor(tt.start), tt.start, len(tt.want)), tt.want)
+		})
+	}
+}
+
+func TestNextTermination(t *testing.T) {
+	start := date(2026, time.January, 18, 9, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		rule string
+		want []time.Time
+	}{
+		{
+			name: "COUNT=3 includes the first occurrence",
+			rule: "FREQ=DAILY;COUNT=3",
+			want: []time.Time{
+				date(2026, time.January, 19, 9, 0, time.UTC),
+				date(2026, time.January, 20, 9, 0, time.UTC),
+			},
+		},
+		{
+			name: "COUNT=1 has no next occurrence",
+			rule: "FREQ=WEEKLY;COUNT=1",
+			want: nil,
+		},
+		{
+			name: "UNTIL is exclusive of later occurrences",
+			rule: "FREQ=DAILY;UNTIL=20260120T000000Z",
+			want: []time.Time{
+				date(2026, time.January, 19, 9, 0, time.UTC),
+			},
+		},
+		{
+			name: "UNTIL is inclusive of an exact match",
+			rule: "FREQ=DAILY;INTERVAL=2;UNTIL=20260122T090000Z",
+			want: []time.Time{
+				date(2026, time.January, 20, 9, 0, time.UTC),
+				date(2026, time.January, 22, 9, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.rule, err)
+			}
+			// Minta lebih banyak dari yang diharapkan supaya terminasi ikut teruji
+			assertSeries(t, series(t, rule.Anchor(start), start, len(tt.want)+5), tt.want)
+		})
+	}
+}
+
+func TestNextAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{
+			// DST mulai 8 Maret 2026 (jam 02:00 loncat ke 03:00)
+			name:  "daily across spring forward",
+			rule:  "FREQ=DAILY",
+			start: date(2026, time.March, 7, 9, 0, newYork),
+			want: []time.Time{
+				date(2026, time.March, 8, 9, 0, newYork),
+				date(2026, time.March, 9, 9, 0, newYork),
+			},
+		},
+		{
+			// DST selesai 1 November 2026 (jam 02:00 mundur ke 01:00)
+			name:  "daily across fall back",
+			rule:  "FREQ=DAILY",
+			start: date(2026, time.October, 31, 9, 0, newYork),
+			want: []time.Time{
+				date(2026, time.November, 1, 9, 0, newYork),
+				date(2026, time.November, 2, 9, 0, newYork),
+			},
+		},
+		{
+			name:  "weekly across spring forward",
+			rule:  "FREQ=WEEKLY;BYDAY=FR",
+			start: date(2026, time.March, 6, 23, 30, newYork),
+			want: []time.Time{
+				date(2026, time.March, 13, 23, 30, newYork),
+			},
+		},
+		{
+			name:  "monthly last day across fall back",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: date(2026, time.October, 31, 7, 15, newYork),
+			want: []time.Time{
+				date(2026, time.November, 30, 7, 15, newYork),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.rule, err)
+			}
+
+			got := series(t, rule.Anchor(tt.start), tt.start, len(tt.want))
+			assertSeries(t, got, tt.want)
+
+			// Jam dinding tetap sama walau offset UTC berubah
+			for _, next := range got {
+				if next.Hour() != tt.start.Hour() || next.Minute() != tt.start.Minute() {
+					t.Errorf("occurrence %s has wall clock %02d:%02d, want %02d:%02d",
+						next, next.Hour(), next.Minute(), tt.start.Hour(), tt.start.Minute())
+				}
+			}
+		})
+	}
+
+	// Interval absolut melewati spring forward hanya 23 jam
+	spring, _ := Parse("FREQ=DAILY")
+	next, _ := spring.Next(date(2026, time.March, 7, 9, 0, newYork), 1)
+	if gap := next.Sub(date(2026, time.March, 7, 9, 0, newYork)); gap != 23*time.Hour {
+		t.Errorf("gap across spring forward = %s, want 23h", gap)
+	}
+}
@@ -1,25 +1,45 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/MashuNakamura/todolist-backend/controllers"
 	"github.com/MashuNakamura/todolist-backend/middleware"
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupRoutes(app *fiber.App) {
+	// Public Key untuk verifikasi JWT oleh service lain
+	app.Get("/.well-known/jwks.json", controllers.JWKS)
+
 	// API Route
 	api := app.Group("/api") // API Route
 
 	// Health Check API Route
 	api.Get("/health", controllers.HealthCheck) // Health Check
 
+	// Rate Limit untuk Auth Route (per IP & per Email, sliding window)
+	loginIPLimit := middleware.RateLimit("login-ip", 20, time.Minute, middleware.ByIP)
+	loginEmailLimit := middleware.RateLimit("login-email", 10, 15*time.Minute, middleware.ByEmail)
+	registerIPLimit := middleware.RateLimit("register-ip", 5, time.Hour, middleware.ByIP)
+	forgotIPLimit := middleware.RateLimit("forgot-ip", 5, 15*time.Minute, middleware.ByIP)
+	forgotEmailLimit := middleware.RateLimit("forgot-email", 3, 15*time.Minute, middleware.ByEmail)
+	resetIPLimit := middleware.RateLimit("reset-ip", 10, 15*time.Minute, middleware.ByIP)
+	resetEmailLimit := middleware.RateLimit("reset-email", 10, 15*time.Minute, middleware.ByEmail)
+
 	// User API Route
-	api.Post("/register", controllers.Register)                 // Register
-	api.Post("/login", controllers.Login)                       // Login
-	api.Post("/forgot-password", controllers.ForgotPassword)    // Forgot Password
-	api.Post("/reset-password", controllers.ResetPassword)      // Reset Password
-	api.Get("/auth/google/login", middleware.GoogleLogin)       // Redirect ke Google
-	api.Get("/auth/google/callback", middleware.GoogleCallback) // Callback dari Google
+	api.Post("/register", registerIPLimit, controllers.Register)                              // Register
+	api.Post("/login", loginIPLimit, loginEmailLimit, controllers.Login)                      // Login
+	api.Post("/forgot-password", forgotIPLimit, forgotEmailLimit, controllers.ForgotPassword) // Forgot Password
+	api.Post("/reset-password", resetIPLimit, resetEmailLimit, controllers.ResetPassword)     // Reset Password
+	api.Post("/token/refresh", controllers.RefreshToken)                                      // Refresh Token (Rotation)
+	api.Post("/login/2fa", loginIPLimit, controllers.VerifyTwoFactorLogin)                    // Login Tahap 2 (Kode 2FA)
+	api.Post("/verify-email", controllers.VerifyEmail)                                        // Verify Email
+	api.Post("/resend-verification", controllers.ResendVerification)                          // Resend Email Verification
+	api.Get("/auth/:provider/login", middleware.OAuthLogin)                                   // Redirect ke Provider (google, github, oidc)
+	api.Get("/auth/:provider/callback", middleware.OAuthCallback)                             // Callback dari Provider
+	api.Post("/auth/exchange", loginIPLimit, controllers.ExchangeOAuthCode)                   // Tukar Kode Login OAuth dengan Token
+	api.Get("/avatars/:userId/:version/:file", controllers.GetAvatar)                         // Gambar Avatar (public, immutable)
 
 	// Protected Route
 	protected := api.Group("/", middleware.Protected)
@@ -27,18 +47,109 @@ func SetupRoutes(app *fiber.App) {
 	protected.Post("/update-profile", controllers.UpdateProfile)   // Update Profile
 	protected.Get("/profile", controllers.GetProfile)              // Read One User
 	protected.Post("/change-password", controllers.ChangePassword) // Change Password
+	protected.Post("/set-password", controllers.SetPassword)       // Set Password Pertama (Akun Social Login)
+
+	// Preferences API Route
+	protected.Get("/profile/preferences", controllers.GetPreferences)    // Read Preferences
+	protected.Put("/profile/preferences", controllers.UpdatePreferences) // Update Preferences
+
+	// Avatar API Route
+	protected.Post("/profile/avatar", controllers.UploadAvatar)   // Upload Foto Profil (multipart)
+	protected.Delete("/profile/avatar", controllers.DeleteAvatar) // Hapus Foto Profil
+
+	// Change Email API Route
+	protected.Post("/change-email", controllers.RequestEmailChange)         // Request Ganti Email (kode ke email baru)
+	protected.Post("/change-email/confirm", controllers.ConfirmEmailChange) // Konfirmasi Ganti Email
+
+	// Two-Factor Authentication API Route
+	protected.Post("/2fa/setup", controllers.SetupTwoFactor)                   // Generate Secret & QR URI
+	protected.Post("/2fa/confirm", controllers.ConfirmTwoFactor)               // Aktifkan 2FA
+	protected.Post("/2fa/recovery-codes", controllers.RegenerateRecoveryCodes) // Generate Ulang Recovery Codes
+	protected.Post("/2fa/disable", controllers.DisableTwoFactor)               // Nonaktifkan 2FA
+
+	// Linked Identity API Route
+	protected.Get("/identities", controllers.GetIdentities)            // List Linked Accounts
+	protected.Post("/identities/:provider/link", middleware.OAuthLink) // Link Account (return URL provider)
+	protected.Delete("/identities/:id", controllers.UnlinkIdentity)    // Unlink Account
+
+	// Session API Route
+	protected.Get("/sessions", controllers.GetSessions)            // List Active Sessions
+	protected.Delete("/sessions", controllers.RevokeOtherSessions) // Logout Everywhere Else
+	protected.Delete("/sessions/:id", controllers.RevokeSession)   // Revoke One Session
+
+	// Personal Access Token API Route
+	protected.Post("/tokens", controllers.CreateAPIToken)       // Create Token
+	protected.Get("/tokens", controllers.GetAPITokens)          // List Tokens
+	protected.Delete("/tokens/:id", controllers.RevokeAPIToken) // Revoke Token
+
+	// Account Data API Route (Export & Hapus Akun)
+	protected.Get("/account/export", controllers.ExportAccount)                 // Export JSON / Zip CSV
+	protected.Post("/account/delete/otp", controllers.SendAccountDeletionCode)  // Kirim OTP Konfirmasi (akun tanpa password)
+	protected.Post("/account/delete", controllers.DeleteAccount)                // Jadwalkan Hapus Akun
+	protected.Post("/account/delete/cancel", controllers.CancelAccountDeletion) // Batal Hapus Akun
+
+	// Admin API Route (hanya login session dengan role admin)
+	admin := protected.Group("/admin", middleware.RequireRole("admin"))
+	admin.Get("/users", controllers.AdminGetUsers)                                     // List & Search User
+	admin.Get("/users/:id", controllers.AdminGetUser)                                  // Detail User + Jumlah Task
+	admin.Post("/users/:id/disable", controllers.AdminDisableUser)                     // Disable Akun
+	admin.Post("/users/:id/enable", controllers.AdminEnableUser)                       // Enable Akun
+	admin.Post("/users/:id/force-password-reset", controllers.AdminForcePasswordReset) // Paksa Reset Password
+	admin.Put("/users/:id/role", controllers.AdminUpdateUserRole)                      // Update Role
+
+	// Akun yang belum verifikasi email dibatasi untuk route di bawah ini
+	protected.Use(middleware.VerifiedEmail)
+
+	// Scope Personal Access Token (route tanpa scope hanya bisa diakses dengan login session)
+	tasksRead := middleware.RequireScope("tasks:read")
+	tasksWrite := middleware.RequireScope("tasks:write")
+	categoriesRead := middleware.RequireScope("categories:read")
+	categoriesWrite := middleware.RequireScope("categories:write")
 
 	// Task API Route
-	protected.Post("/tasks", controllers.CreateTask)              // Create
-	protected.Get("/tasks", controllers.GetAllTasks)              // Read All
-	protected.Get("/tasks/:id", controllers.GetTaskByID)          // Read One
-	protected.Put("/tasks/:id", controllers.UpdateTask)           // Update
-	protected.Delete("/tasks", controllers.DeleteTask)            // Delete Batch Task
-	protected.Put("/tasks/status", controllers.UpdateBatchStatus) // Update Batch Status
+	protected.Post("/tasks", tasksWrite, controllers.CreateTask)              // Create
+	protected.Get("/tasks", tasksRead, controllers.GetAllTasks)               // Read All
+	protected.Get("/tasks/:id", tasksRead, controllers.GetTaskByID)           // Read One
+	protected.Put("/tasks/status", tasksWrite, controllers.UpdateBatchStatus) // Update Batch Status (harus sebelum /tasks/:id)
+	protected.Put("/tasks/:id", tasksWrite, controllers.UpdateTask)           // Update
+	protected.Delete("/tasks", tasksWrite, controllers.DeleteTask)            // Delete Batch Task
+
+	// Subtask API Route
+	protected.Get("/tasks/:id/subtasks", tasksRead, controllers.GetSubtasks)                  // Read All
+	protected.Post("/tasks/:id/subtasks", tasksWrite, controllers.CreateSubtask)              // Create
+	protected.Put("/tasks/:id/subtasks/:subtaskId", tasksWrite, controllers.UpdateSubtask)    // Update
+	protected.Delete("/tasks/:id/subtasks/:subtaskId", tasksWrite, controllers.DeleteSubtask) // Delete
+
+	// Attachment API Route
+	protected.Get("/tasks/:id/attachments", tasksRead, controllers.GetAttachments)                     // Read All
+	protected.Post("/tasks/:id/attachments", tasksWrite, controllers.UploadAttachment)                 // Upload (multipart)
+	protected.Get("/tasks/:id/attachments/:attachmentId", tasksRead, controllers.DownloadAttachment)   // Download
+	protected.Delete("/tasks/:id/attachments/:attachmentId", tasksWrite, controllers.DeleteAttachment) // Delete
+
+	// Comment API Route
+	protected.Get("/tasks/:id/comments", tasksRead, controllers.GetComments)                  // Read All
+	protected.Post("/tasks/:id/comments", tasksWrite, controllers.CreateComment)              // Create
+	protected.Put("/tasks/:id/comments/:commentId", tasksWrite, controllers.UpdateComment)    // Update
+	protected.Delete("/tasks/:id/comments/:commentId", tasksWrite, controllers.DeleteComment) // Delete (soft)
+
+	// Activity API Route
+	protected.Get("/tasks/:id/activity", tasksRead, controllers.GetTaskActivity) // Timeline Task
+	protected.Get("/activity", tasksRead, controllers.GetActivityFeed)           // Feed User
 
 	// Category API Route
-	protected.Post("/categories", controllers.CreateCategory)       // Create
-	protected.Get("/categories", controllers.GetCategoriesByUser)   // Read All
-	protected.Put("/categories/:id", controllers.UpdateCategory)    // Update
-	protected.Delete("/categories/:id", controllers.DeleteCategory) // Delete
+	protected.Post("/categories", categoriesWrite, controllers.CreateCategory)       // Create
+	protected.Get("/categories", categoriesRead, controllers.GetCategoriesByUser)    // Read All
+	protected.Put("/categories/:id", categoriesWrite, controllers.UpdateCategory)    // Update
+	protected.Delete("/categories/:id", categoriesWrite, controllers.DeleteCategory) // Delete
+
+	// Trash API Route
+	protected.Get("/trash/tasks", tasksRead, controllers.GetTrashedTasks)                       // Read All Trashed Task
+	protected.Post("/trash/tasks/restore", tasksWrite, controllers.RestoreTasks)                // Restore Batch Task
+	protected.Delete("/trash/tasks", tasksWrite, controllers.PurgeTasks)                        // Purge Batch Task
+	protected.Get("/trash/categories", categoriesRead, controllers.GetTrashedCategories)        // Read All Trashed Category
+	protected.Post("/trash/categories/restore", categoriesWrite, controllers.RestoreCategories) // Restore Batch Category
+	protected.Delete("/trash/categories", categoriesWrite, controllers.PurgeCategories)         // Purge Batch Category
+
+	// Sync API Route
+	protected.Get("/sync", tasksRead, categoriesRead, controllers.Sync) // Incremental Sync
 }
@@ -0,0 +1,94 @@
+// Package storage menyimpan file upload (avatar, attachment) lewat interface
+// blob kecil, jadi backend bisa diganti tanpa mengubah controller.
+//
+// Backend dipilih dengan STORAGE_DRIVER:
+//
+//	local (default)  file di STORAGE_DIR (default ./uploads)
+//	s3               layanan S3-compatible, lihat NewS3Store
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid object key")
+)
+
+// Blob store key/value datar, key berupa path dipisah slash seperti "avatars/12/abc/256.jpg"
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultStore Store
+	defaultErr   error
+)
+
+// Konfigurasi store default sekali saja, panggil saat startup supaya error langsung ketahuan
+func Init() error {
+	defaultOnce.Do(func() {
+		switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+		case "", "local":
+			dir := os.Getenv("STORAGE_DIR")
+			if dir == "" {
+				dir = "uploads"
+			}
+			defaultStore, defaultErr = NewLocalStore(dir)
+		case "s3":
+			defaultStore, defaultErr = NewS3StoreFromEnv()
+		default:
+			defaultErr = fmt.Errorf("storage: unknown STORAGE_DRIVER %q (local, s3)", driver)
+		}
+	})
+	return defaultErr
+}
+
+// Store yang sedang dipakai
+func Default() Store {
+	if err := Init(); err != nil {
+		return failingStore{err}
+	}
+	return defaultStore
+}
+
+// Hapus semua key, object yang sudah tidak ada diabaikan
+func DeleteAll(ctx context.Context, store Store, keys ...string) error {
+	var errs []error
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Tolak key kosong dan path traversal
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// Store yang dipakai kalau konfigurasi storage gagal (error dilaporkan ke caller)
+type failingStore struct{ err error }
+
+func (s failingStore) Put(context.Context, string, io.Reader, int64, string) error { return s.err }
+func (s failingStore) Get(context.Context, string) (io.ReadCloser, error)          { return nil, s.err }
+func (s failingStore) Delete(context.Context, string) error                        { return s.err }